@@ -0,0 +1,22 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"sdk-ui-go/internal"
+)
+
+// bootstrapTool runs install and shows why it failed, e.g. when the download
+// does not match the pinned hash. A missing hash is only logged, since it
+// would otherwise be reported on every start.
+func bootstrapTool(title string, install func() error) error {
+	err := install()
+	if errors.Is(err, internal.ErrNoInstallerHash) {
+		slog.Warn("skipping install", "title", title, "err", err)
+		return err
+	}
+	if err != nil {
+		showError(title, err)
+	}
+	return err
+}
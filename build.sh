@@ -6,7 +6,7 @@ IDENTIFIER="com.shanzhengliu.sdkuigo"
 ICON_FILE="icon.icns"  # 指向你的图标文件
 
 # 编译 Go 应用程序
-go build -o ${EXECUTABLE_NAME} main.go
+go build -o ${EXECUTABLE_NAME} .
 
 # 创建应用包目录结构
 mkdir -p ${APP_NAME}.app/Contents/MacOS
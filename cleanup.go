@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"github.com/getlantern/systray"
+	"github.com/ncruces/zenity"
+	"log/slog"
+	"sdk-ui-go/internal"
+	"strings"
+	"time"
+)
+
+const retentionInterval = 24 * time.Hour
+
+func addCleanupMenu() {
+	cleanupItem := systray.AddMenuItem("Clean Up", "")
+	pruneItem := cleanupItem.AddSubMenuItem("Old Versions…", "Preview and remove what the retention policy would prune")
+	projectItem := cleanupItem.AddSubMenuItem("Add Project Folder…", "Never prune the versions a project's .sdkmanrc or .nvmrc uses")
+	go func() {
+		for {
+			select {
+			case <-pruneItem.ClickedCh:
+				reviewRetention()
+			case <-projectItem.ClickedCh:
+				addProject()
+			}
+		}
+	}()
+}
+
+// planRetention is the dry run of the retention policies, minus anything
+// checked in a menu right now in case the menus know better than the disk.
+func planRetention() ([]internal.PruneCandidate, error) {
+	plan, err := internal.PlanRetention(internal.CurrentConfig())
+	if err != nil {
+		return nil, err
+	}
+	var safe []internal.PruneCandidate
+	for _, p := range plan {
+		if isCheckedInMenu(p.Tool, p.Version) {
+			slog.Info("keeping version checked in the menu", "tool", p.Tool, "version", p.Version)
+			continue
+		}
+		safe = append(safe, p)
+	}
+	return safe, nil
+}
+
+func isCheckedInMenu(tool string, version string) bool {
+	key := tool
+	if tool == internal.NodeWatchKey {
+		key = nodeMenuKey
+	}
+	candidateMu.Lock()
+	defer candidateMu.Unlock()
+	for _, menu := range candidate[key] {
+		if menu.Version == version && menu.MenuItem.Checked() {
+			return true
+		}
+	}
+	return false
+}
+
+// reviewRetention previews the plan with everything selected and removes
+// what the user leaves checked after confirming.
+func reviewRetention() {
+	if len(internal.CurrentConfig().Retention) == 0 {
+		zenity.Info("No retention policy is set up yet. Add one per tool under \"retention\" in the settings, e.g.\n\n\"retention\": { \"java\": { \"keep_patches\": 2, \"pinned\": [\"17\"] } }",
+			zenity.Title("Clean Up"))
+		return
+	}
+	plan, err := planRetention()
+	if err != nil {
+		showError("Clean Up", err)
+		return
+	}
+	if len(plan) == 0 {
+		zenity.Info("The retention policy has nothing to remove.", zenity.Title("Clean Up"))
+		return
+	}
+	var labels []string
+	byLabel := make(map[string]internal.PruneCandidate)
+	for _, p := range plan {
+		labels = append(labels, p.String())
+		byLabel[p.String()] = p
+	}
+	chosen, err := zenity.ListMultiple("The retention policy would uninstall these versions. Uncheck any you want to keep.",
+		labels, zenity.Title("Clean Up"), zenity.CheckList(), zenity.DefaultItems(labels...))
+	if err != nil || len(chosen) == 0 {
+		return
+	}
+	err = zenity.Question(fmt.Sprintf("Uninstall %d versions?\n\n%s", len(chosen), strings.Join(chosen, "\n")),
+		zenity.Title("Clean Up"), zenity.OKLabel("Uninstall"))
+	if err != nil {
+		return
+	}
+	for _, label := range chosen {
+		p := byLabel[label]
+		enqueueUninstall(p.Tool, p.Version)
+	}
+}
+
+// retentionScheduleInterval runs the policy once a day unless the schedule
+// is off. In notify mode it only reports what could go; in auto mode it
+// removes it.
+func retentionScheduleInterval() time.Duration {
+	if internal.CurrentConfig().RetentionSchedule == internal.RetentionOff {
+		return 0
+	}
+	return retentionInterval
+}
+
+func applyScheduledRetention() {
+	schedule := internal.CurrentConfig().RetentionSchedule
+	if schedule == internal.RetentionOff {
+		return
+	}
+	plan, err := planRetention()
+	if err != nil {
+		slog.Error("planning retention", "err", err)
+		return
+	}
+	if len(plan) == 0 {
+		return
+	}
+	slog.Info("retention plan", "schedule", schedule, "versions", len(plan))
+	if schedule == internal.RetentionNotify {
+		internal.Notify("Clean Up", fmt.Sprintf("%d old versions can be removed, see Clean Up > Old Versions", len(plan)))
+		return
+	}
+	for _, p := range plan {
+		enqueueUninstall(p.Tool, p.Version)
+	}
+	internal.Notify("Clean Up", fmt.Sprintf("Removing %d old versions", len(plan)))
+}
+
+func addProject() {
+	dir, err := zenity.SelectFile(zenity.Title("Add Project Folder"), zenity.Directory())
+	if err != nil {
+		return
+	}
+	err = internal.UpdateConfig(func(cfg *internal.Config) {
+		for _, project := range cfg.Projects {
+			if project == dir {
+				return
+			}
+		}
+		cfg.Projects = append(cfg.Projects, dir)
+	})
+	if err != nil {
+		showError("Add Project Folder", err)
+		return
+	}
+	internal.Notify("Clean Up", "Versions used by "+dir+" will be kept")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/getlantern/systray"
+	"github.com/ncruces/zenity"
+	"sdk-ui-go/internal"
+)
+
+// addPackageManagersMenu fills item with the npm, corepack, pnpm and yarn
+// state of an installed Node version and returns the function that
+// re-reads it.
+func addPackageManagersMenu(item *systray.MenuItem, version string) func() {
+	npmItem := item.AddSubMenuItem("npm", "Upgrade the npm of this Node version")
+	corepackItem := item.AddSubMenuItemCheckbox("Corepack", "Provide pnpm and yarn through corepack, honouring packageManager in package.json", false)
+	pinItems := make(map[string]*systray.MenuItem)
+	for _, name := range internal.PinnablePackageManagers {
+		pinItems[name] = item.AddSubMenuItem(name, "Pin the "+name+" version of this Node version")
+	}
+	refresh := func() {
+		state := internal.NodePackageManagers(version)
+		npmItem.SetTitle("npm " + orNone(state.NPM) + ": Upgrade…")
+		switch {
+		case state.CorepackEnabled:
+			corepackItem.SetTitle("Corepack " + state.Corepack + ": Enabled")
+			corepackItem.Check()
+		case state.Corepack != "":
+			corepackItem.SetTitle("Corepack " + state.Corepack + ": Disabled")
+			corepackItem.Uncheck()
+		default:
+			corepackItem.SetTitle("Corepack: Not Bundled, Install && Enable")
+			corepackItem.Uncheck()
+		}
+		for name, pinItem := range pinItems {
+			pinItem.SetTitle(name + " " + orNone(state.Versions[name]) + ": Pin Version…")
+			if state.CorepackEnabled {
+				pinItem.SetTooltip("Pinned through corepack for every Node version with corepack enabled")
+			} else {
+				pinItem.SetTooltip("Installed globally with npm")
+			}
+		}
+	}
+	run := func(action string, detail string, title string, command func() (string, error)) {
+		enqueue(internal.Operation{Provider: internal.ProviderNVM, Action: action, Tool: internal.NodeWatchKey, Version: version, Detail: detail, Run: func() (string, error) {
+			out, err := command()
+			refresh()
+			if err != nil {
+				return out, failed(title, err)
+			}
+			internal.Notify(title, title+" for node "+version+" is done")
+			return out, nil
+		}})
+	}
+	pin := func(name string) {
+		title := "Pin " + name
+		spec, err := zenity.Entry("Version of "+name+" for node "+version+", e.g. 9.1.0:", zenity.Title(title))
+		if err != nil || spec == "" {
+			return
+		}
+		run("pin", name, title, func() (string, error) { return internal.PinPackageManager(version, name, spec) })
+	}
+	go func() {
+		for {
+			select {
+			case <-pinItems["pnpm"].ClickedCh:
+				pin("pnpm")
+			case <-pinItems["yarn"].ClickedCh:
+				pin("yarn")
+			case <-npmItem.ClickedCh:
+				spec, err := zenity.Entry("npm version for node "+version+". Leave empty for the latest one that supports it:", zenity.Title("Upgrade npm"))
+				if err != nil {
+					continue
+				}
+				run("npm", "", "Upgrade npm", func() (string, error) { return internal.UpgradeNPM(version, spec) })
+			case <-corepackItem.ClickedCh:
+				enable := !corepackItem.Checked()
+				title := "Enable Corepack"
+				if !enable {
+					title = "Disable Corepack"
+				}
+				run("corepack", "", title, func() (string, error) { return internal.SetCorepack(version, enable) })
+			}
+		}
+	}()
+	return refresh
+}
+
+func orNone(version string) string {
+	if version == "" {
+		return "(none)"
+	}
+	return version
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log/slog"
+	"sdk-ui-go/internal"
+	"strings"
+	"time"
+)
+
+const eolCheckInterval = 24 * time.Hour
+
+// eolStatus looks up menu's version in the end of life data.
+func eolStatus(menu *VersionMenu) (string, time.Time) {
+	tool := menu.Title
+	if tool == nodeMenuKey {
+		tool = internal.NodeWatchKey
+	}
+	warn := time.Duration(internal.CurrentConfig().EOLWarningDays) * 24 * time.Hour
+	return internal.CurrentEOLData().Status(tool, menu.Version, time.Now(), warn)
+}
+
+// eolMarker prefixes installed versions at or near their end of life.
+func eolMarker(menu *VersionMenu) string {
+	if status, _ := eolStatus(menu); status != internal.EOLNone {
+		return "⚠ "
+	}
+	return ""
+}
+
+func eolTooltip(menu *VersionMenu) string {
+	switch status, end := eolStatus(menu); status {
+	case internal.EOLPast:
+		return "End of life since " + end.Format(time.DateOnly)
+	case internal.EOLSoon:
+		return "End of life on " + end.Format(time.DateOnly)
+	}
+	return ""
+}
+
+// versionTooltip is an installed version's size and end of life note.
+func versionTooltip(menu *VersionMenu) string {
+	var lines []string
+	for _, line := range []string{sizeTooltip(menu), eolTooltip(menu)} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checkEOL refreshes the end of life data from eol_url, re-marks the menus
+// and notifies once per default version that reached its end of life.
+func checkEOL() {
+	if url := internal.CurrentConfig().EOLURL; url != "" {
+		if err := internal.RefreshEOLData(url); err != nil {
+			slog.Warn("refreshing EOL data", "url", url, "err", err)
+		}
+	}
+	for _, source := range allMenuSources() {
+		source.refreshLocal()
+	}
+	ended, err := internal.NewlyEndedDefaults()
+	if err != nil {
+		slog.Error("checking default versions for end of life", "err", err)
+		return
+	}
+	if len(ended) == 0 {
+		return
+	}
+	names := make([]string, len(ended))
+	for i, finding := range ended {
+		names[i] = finding.String()
+	}
+	internal.Notify("End of Life", "The default is past its end of life: "+strings.Join(names, ", "))
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/getlantern/systray"
+	"log/slog"
+	"os"
+	"sdk-ui-go/internal"
+	"text/tabwriter"
+	"time"
+)
+
+const historyMenuSize = 10
+
+type HistoryMenu struct {
+	slots    []*systray.MenuItem
+	openItem *systray.MenuItem
+}
+
+func newHistoryMenu(item *systray.MenuItem) *HistoryMenu {
+	menu := &HistoryMenu{}
+	for i := 0; i < historyMenuSize; i++ {
+		slot := item.AddSubMenuItem("", "")
+		slot.Disable()
+		slot.Hide()
+		menu.slots = append(menu.slots, slot)
+	}
+	menu.openItem = item.AddSubMenuItem("Open History File", "")
+	go func() {
+		for range menu.openItem.ClickedCh {
+			path, err := internal.HistoryFilePath()
+			if err != nil {
+				slog.Error("locating history", "err", err)
+				continue
+			}
+			internal.OpenPath(path)
+		}
+	}()
+	menu.refresh()
+	return menu
+}
+
+// refresh shows the most recent entries, newest first.
+func (m *HistoryMenu) refresh() {
+	entries, err := internal.ReadHistory(internal.HistoryFilter{Limit: historyMenuSize})
+	if err != nil {
+		slog.Error("reading history", "err", err)
+	}
+	for i, slot := range m.slots {
+		if i >= len(entries) {
+			slot.Hide()
+			continue
+		}
+		entry := entries[len(entries)-1-i]
+		slot.SetTitle(entry.String())
+		slot.SetTooltip(entry.Output)
+		slot.Show()
+	}
+}
+
+func historyCommand(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	tool := fs.String("tool", "", "only show entries for this tool, e.g. java")
+	provider := fs.String("provider", "", "only show entries for this provider (sdkman or nvm)")
+	action := fs.String("action", "", "only show entries for this action, e.g. install")
+	since := fs.Duration("since", 0, "only show entries newer than this, e.g. 720h")
+	limit := fs.Int("limit", 50, "maximum number of entries, 0 for all")
+	asJSON := fs.Bool("json", false, "print entries as JSON lines")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	filter := internal.HistoryFilter{Tool: *tool, Provider: *provider, Action: *action, Limit: *limit}
+	if *since > 0 {
+		filter.Since = time.Now().Add(-*since)
+	}
+	entries, err := internal.ReadHistory(filter)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading history:", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, entry := range entries {
+			encoder.Encode(entry)
+		}
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "START\tDURATION\tUSER\tHOST\tPROVIDER\tACTION\tTOOL\tVERSION\tEXIT")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
+			e.Start.Format(time.RFC3339), e.End.Sub(e.Start).Round(time.Second), e.User, e.Host,
+			e.Provider, e.Action, e.Tool, e.Version, e.ExitCode)
+	}
+	w.Flush()
+	return 0
+}
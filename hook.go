@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ncruces/zenity"
+	"io"
+	"log/slog"
+	"os"
+	"sdk-ui-go/internal"
+	"sync"
+)
+
+var (
+	promptedInstalls   = make(map[string]bool)
+	promptedInstallsMu sync.Mutex
+)
+
+// hookCommand prints the script that makes bash or zsh run `env` on every cd:
+//
+//	eval "$(sdkuigo hook zsh)"
+func hookCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: sdkuigo hook bash|zsh")
+		return 2
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error locating sdkuigo:", err)
+		return 1
+	}
+	script, err := internal.ShellHook(args[0], executable)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 2
+	}
+	fmt.Print(script)
+	return 0
+}
+
+// envCommand prints the exports for the versions the current directory pins
+// in .sdkmanrc and .nvmrc. Anything but shell code goes to stderr, since the
+// hook evals stdout.
+func envCommand() int {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	internal.LoadConfig()
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sdkuigo:", err)
+		return 1
+	}
+	env, err := internal.ResolveProjectEnv(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sdkuigo:", err)
+		return 1
+	}
+	fmt.Print(internal.ShellExports(env))
+	for _, missing := range env.Missing {
+		message := fmt.Sprintf("sdkuigo: %s %s from %s is not installed", missing.Tool, missing.Version, missing.File)
+		if internal.CurrentConfig().PromptMissingVersions {
+			req := internal.InstallRequest{Tool: missing.Tool, Version: missing.Version, File: missing.File}
+			if err := internal.RequestInstall(req); err != nil {
+				message += "; start SDK UI to install it"
+			} else {
+				message += "; SDK UI will offer to install it"
+			}
+		}
+		fmt.Fprintln(os.Stderr, message)
+	}
+	return 0
+}
+
+// promptProjectInstall asks once per session whether to install a version a
+// project pins, and installs it without changing the default.
+func promptProjectInstall(req internal.InstallRequest) {
+	key := req.Tool + "@" + req.Version
+	promptedInstallsMu.Lock()
+	prompted := promptedInstalls[key]
+	promptedInstalls[key] = true
+	promptedInstallsMu.Unlock()
+	if prompted {
+		return
+	}
+	if offline.Load() {
+		internal.Notify("Install", req.Tool+" "+req.Version+" is needed by "+req.File+" but SDK UI is offline")
+		return
+	}
+	err := zenity.Question(req.File+" uses "+req.Tool+" "+req.Version+", which is not installed.\n\nInstall it now? The default version stays unchanged.",
+		zenity.Title("Install "+req.Tool), zenity.OKLabel("Install"), zenity.CancelLabel("Not now"))
+	if err != nil {
+		return
+	}
+	if req.Tool == internal.NodeWatchKey {
+		enqueue(internal.Operation{Provider: internal.ProviderNVM, Action: "install", Tool: "node", Version: req.Version, Run: func() (string, error) {
+			return runProjectInstall(req, internal.NodeCacheKey, func() (string, error) { return internal.InstallNodeVersion(req.Version) })
+		}})
+		return
+	}
+	enqueue(internal.Operation{Provider: internal.ProviderSDKMan, Action: "install", Tool: req.Tool, Version: req.Version, Run: func() (string, error) {
+		return runProjectInstall(req, internal.SDKManCacheKey(req.Tool), func() (string, error) {
+			return internal.InstallCandidate(req.Tool, req.Version, sdkmanInitScript)
+		})
+	}})
+}
+
+func runProjectInstall(req internal.InstallRequest, cacheKey string, install func() (string, error)) (string, error) {
+	internal.Notify("Install", "Installing "+req.Tool+" "+req.Version)
+	out, err := install()
+	if err != nil {
+		return out, failed("Install failed", err)
+	}
+	internal.InvalidateCache(cacheKey)
+	internal.Notify("Install", req.Tool+" "+req.Version+" has installed, cd into the project again to use it")
+	return out, nil
+}
@@ -0,0 +1,15 @@
+package internal
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime reads the access time from stat.
+func accessTime(info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Atimespec.Sec, stat.Atimespec.Nsec)
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,16 @@
+package internal
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime reads the access time from stat; volumes mounted noatime or
+// relatime make it coarse, so it is only a hint of last use.
+func accessTime(info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Atim.Sec, stat.Atim.Nsec)
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package internal
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime falls back to the modification time where the access time is
+// not wired up.
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,16 @@
+package internal
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime reads the last access time NTFS keeps, which Windows may only
+// update lazily.
+func accessTime(info os.FileInfo) time.Time {
+	if data, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, data.LastAccessTime.Nanoseconds())
+	}
+	return info.ModTime()
+}
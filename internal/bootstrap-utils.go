@@ -0,0 +1,299 @@
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// The SDKMan release zip is what get.sdkman.io unpacks; unlike the get
+	// script it does not change between releases, so it can be pinned.
+	DefaultSDKManInstaller = "https://github.com/sdkman/sdkman-cli/releases/download/{version}/sdkman-cli-{version}.zip"
+	DefaultSDKManVersion   = "5.18.2"
+	DefaultNVMInstaller    = "https://raw.githubusercontent.com/nvm-sh/nvm/{version}/install.sh"
+	DefaultNVMVersion      = "v0.40.3"
+
+	bootstrapTimeout = 2 * time.Minute
+)
+
+// NVMInstallerSHA256 pins the install.sh of the NVM releases the app knows.
+var NVMInstallerSHA256 = map[string]string{
+	"v0.40.3": "2d8359a64a3cb07c02389ad88ceecd43f2fa469c06104f92f98df5b6f315275f",
+}
+
+// SDKManInstallerSHA256 pins the release zips of the SDKMan releases the app
+// knows. A release is only added once its checksum was verified against the
+// published zip, so until then installing SDKMan needs a hash in the
+// settings.
+var SDKManInstallerSHA256 = map[string]string{}
+
+// ErrNoInstallerHash is returned by installs whose installer has no pinned
+// hash, so nothing was downloaded.
+var ErrNoInstallerHash = errors.New("no installer sha256 pinned")
+
+// InstallerConfig says where a tool's installer comes from. Source is a URL,
+// e.g. an internal mirror, or a local path to a downloaded installer script
+// or release archive. {version} in Source is replaced with Version. SHA256
+// pins the installer; nothing is downloaded or run without it.
+type InstallerConfig struct {
+	Source  string `json:"source"`
+	Version string `json:"version,omitempty"`
+	SHA256  string `json:"sha256"`
+}
+
+type BootstrapConfig struct {
+	SDKMan InstallerConfig `json:"sdkman"`
+	NVM    InstallerConfig `json:"nvm"`
+}
+
+func (c InstallerConfig) Location() string {
+	return strings.ReplaceAll(c.Source, "{version}", c.Version)
+}
+
+func (c InstallerConfig) Validate() error {
+	if c.Source == "" {
+		return fmt.Errorf("installer source must not be empty")
+	}
+	if c.SHA256 != "" {
+		if sum, err := hex.DecodeString(c.SHA256); err != nil || len(sum) != sha256.Size {
+			return fmt.Errorf("installer sha256 must be 64 hex characters, got %q", c.SHA256)
+		}
+	}
+	return nil
+}
+
+// bootstrap installs tool into dir from installer: it fetches the installer
+// to a temp file, verifies it against the pinned hash, runs or unpacks it
+// and records what was installed. Without a pinned hash nothing is fetched.
+func bootstrap(tool string, dir string, installer InstallerConfig, run func(path string) error, version func() string) error {
+	location := installer.Location()
+	if installer.SHA256 == "" {
+		return fmt.Errorf("%w for the %s installer from %s; set bootstrap.%s.sha256 to the checksum the project publishes",
+			ErrNoInstallerHash, tool, location, strings.ToLower(tool))
+	}
+	path, sum, err := fetchInstaller(location)
+	if err != nil {
+		return fmt.Errorf("downloading the %s installer from %s: %w", tool, location, err)
+	}
+	defer os.Remove(path)
+	if !strings.EqualFold(sum, installer.SHA256) {
+		return fmt.Errorf("the %s installer from %s has sha256 %s, expected %s", tool, location, sum, installer.SHA256)
+	}
+	slog.Info("verified installer", "tool", tool, "source", location, "sha256", sum)
+
+	existed := FileExists(dir)
+	if err := run(path); err != nil {
+		return err
+	}
+	created := CreatedTool{Tool: tool, Path: dir, Version: version(), Source: location, SHA256: sum}
+	slog.Info("bootstrapped tool", "tool", tool, "version", created.Version, "dir", dir)
+	if existed {
+		// An upgrade keeps the original install record, if there is one,
+		// but notes what it was upgraded to.
+		err := updateCreatedTools(func(tools []CreatedTool) []CreatedTool {
+			for i := range tools {
+				if tools[i].Path == dir {
+					tools[i].Version, tools[i].Source, tools[i].SHA256 = created.Version, created.Source, created.SHA256
+				}
+			}
+			return tools
+		})
+		if err != nil {
+			slog.Warn("recording upgrade", "tool", tool, "err", err)
+		}
+		return nil
+	}
+	if err := RecordCreatedTool(created); err != nil {
+		slog.Warn("recording install", "tool", tool, "err", err)
+	}
+	return nil
+}
+
+// fetchInstaller copies location to a temp file and returns its path and
+// SHA-256. Local paths and file:// URLs are read directly.
+func fetchInstaller(location string) (string, string, error) {
+	var body io.ReadCloser
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		client := http.Client{Timeout: bootstrapTimeout}
+		resp, err := client.Get(location)
+		if err != nil {
+			return "", "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return "", "", fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		body = resp.Body
+	} else {
+		file, err := os.Open(expandHome(strings.TrimPrefix(location, "file://")))
+		if err != nil {
+			return "", "", err
+		}
+		body = file
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp("", "sdk-ui-go-installer-*")
+	if err != nil {
+		return "", "", err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+	return tmp.Name(), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// isGzip reports whether path is a gzip file, i.e. a release tarball rather
+// than an installer script.
+func isGzip(path string) bool {
+	return hasMagic(path, 0x1f, 0x8b)
+}
+
+// isZip reports whether path is a zip file, e.g. an SDKMan release.
+func isZip(path string) bool {
+	return hasMagic(path, 'P', 'K')
+}
+
+func hasMagic(path string, magic ...byte) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(file, head); err != nil {
+		return false
+	}
+	return bytes.Equal(head, magic)
+}
+
+// archiveTarget maps an archive entry to its path under dir, dropping the
+// top-level directory releases are packed in. ok is false for the top-level
+// directory itself.
+func archiveTarget(dir string, entry string) (string, bool, error) {
+	_, name, ok := strings.Cut(entry, "/")
+	if !ok || name == "" {
+		return "", false, nil
+	}
+	target := filepath.Join(dir, name)
+	if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return "", false, fmt.Errorf("archive entry %q escapes %s", entry, dir)
+	}
+	return target, true, nil
+}
+
+func writeArchiveFile(target string, mode os.FileMode, content io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, content)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// extractTarball unpacks a GitHub style release tarball into dir, dropping
+// the top-level directory.
+func extractTarball(path string, dir string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, ok, err := archiveTarget(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, os.FileMode(header.Mode), reader); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// extractZip unpacks a release zip into dir, dropping the top-level
+// directory.
+func extractZip(path string, dir string) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	for _, file := range archive.File {
+		target, ok, err := archiveTarget(dir, file.Name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		content, err := file.Open()
+		if err != nil {
+			return err
+		}
+		mode := file.Mode()
+		if mode.Perm() == 0 {
+			mode = 0644
+		}
+		err = writeArchiveFile(target, mode, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBootstrapWithoutHashFetchesNothing(t *testing.T) {
+	dir := t.TempDir()
+	installer := InstallerConfig{Source: filepath.Join(dir, "missing.zip")}
+	ran := false
+	err := bootstrap("SDKMan", filepath.Join(dir, ".sdkman"), installer, func(string) error {
+		ran = true
+		return nil
+	}, func() string { return "" })
+	if !errors.Is(err, ErrNoInstallerHash) {
+		t.Errorf("got %v, want ErrNoInstallerHash", err)
+	}
+	if ran {
+		t.Error("installer ran without a pinned hash")
+	}
+}
+
+func TestSDKManPlatform(t *testing.T) {
+	tests := map[string]string{
+		"darwin/arm64": "darwinarm64",
+		"darwin/amd64": "darwinx64",
+		"linux/amd64":  "linuxx64",
+		"linux/arm64":  "linuxarm64",
+		"freebsd/386":  "exotic",
+	}
+	for platform, want := range tests {
+		goos, goarch, _ := strings.Cut(platform, "/")
+		if got := sdkmanPlatform(goos, goarch); got != want {
+			t.Errorf("sdkmanPlatform(%s) = %q, want %q", platform, got, want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const DefaultCacheTTL = 24 * time.Hour
+
+type CacheEntry struct {
+	FetchedAt  time.Time   `json:"fetched_at"`
+	ETag       string      `json:"etag"`
+	Candidates []Candidate `json:"candidates,omitempty"`
+	Names      []string    `json:"names,omitempty"`
+}
+
+func (e CacheEntry) Expired(ttl time.Duration) bool {
+	return time.Since(e.FetchedAt) > ttl
+}
+
+func AppCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cacheDir, "sdk-ui-go")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func cacheFilePath(key string) (string, error) {
+	dir, err := AppCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, strings.ToLower(key)+".json"), nil
+}
+
+func LoadCache(key string) (CacheEntry, bool) {
+	var entry CacheEntry
+	path, err := cacheFilePath(key)
+	if err != nil {
+		return entry, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		slog.Warn("ignoring corrupt cache", "key", key, "err", err)
+		return CacheEntry{}, false
+	}
+	return entry, true
+}
+
+// SaveCache stamps entry with the current time and a content hash and writes
+// it atomically, so a crash never leaves a half-written list behind.
+func SaveCache(key string, entry CacheEntry) (CacheEntry, error) {
+	entry.FetchedAt = time.Now()
+	entry.ETag = cacheETag(entry)
+	path, err := cacheFilePath(key)
+	if err != nil {
+		return entry, err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+	return entry, writeCacheFile(path, data)
+}
+
+// writeCacheFile replaces path through a temp file in the same directory, so
+// readers such as PeekCachedNames see either the old or the new list, never
+// a truncated one, even with two writers at once.
+func writeCacheFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// InvalidateCache keeps the cached list but marks it expired, so it is still
+// shown on the next start and refreshed right after.
+func InvalidateCache(key string) {
+	entry, ok := LoadCache(key)
+	if !ok {
+		return
+	}
+	entry.FetchedAt = time.Time{}
+	path, err := cacheFilePath(key)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := writeCacheFile(path, data); err != nil {
+		slog.Warn("invalidating cache", "key", key, "err", err)
+	}
+}
+
+// RefreshCandidateCache fetches a fresh version list, stores it under key and
+// reports whether it differs from previous.
+func RefreshCandidateCache(key string, previous CacheEntry, fetch func() ([]Candidate, error)) (CacheEntry, bool, error) {
+	candidates, err := fetch()
+	if err != nil {
+		return previous, false, err
+	}
+	entry, err := SaveCache(key, CacheEntry{Candidates: candidates})
+	if err != nil {
+		slog.Warn("saving cache", "key", key, "err", err)
+	}
+	return entry, entry.ETag != previous.ETag, nil
+}
+
+// CachedNames returns the names cached under key, fetching them first when
+// there is no cache yet. An expired cache is returned as is and refreshed in
+// the background for the next start.
+func CachedNames(key string, ttl time.Duration, fetch func() ([]string, error)) ([]string, error) {
+	refresh := func() ([]string, error) {
+		names, err := fetch()
+		if err != nil {
+			return names, err
+		}
+		if _, err := SaveCache(key, CacheEntry{Names: names}); err != nil {
+			slog.Warn("saving cache", "key", key, "err", err)
+		}
+		return names, nil
+	}
+	entry, ok := LoadCache(key)
+	if !ok {
+		return refresh()
+	}
+	if entry.Expired(ttl) {
+		go func() {
+			if _, err := refresh(); err != nil {
+				slog.Warn("refreshing cache", "key", key, "err", err)
+			}
+		}()
+	}
+	return entry.Names, nil
+}
+
+// PeekCachedNames returns the names cached under key, expired or not,
+// without ever fetching, for paths that must not wait on the network.
+func PeekCachedNames(key string) ([]string, bool) {
+	entry, ok := LoadCache(key)
+	return entry.Names, ok
+}
+
+func cacheETag(entry CacheEntry) string {
+	data, _ := json.Marshal(struct {
+		Candidates []Candidate
+		Names      []string
+	}{entry.Candidates, entry.Names})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func SDKManCacheKey(candidate string) string {
+	return "sdkman-" + strings.ToLower(candidate)
+}
+
+const (
+	SDKManCandidatesCacheKey = "sdkman-candidates"
+	NodeCacheKey             = "nvm-node"
+)
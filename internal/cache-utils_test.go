@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPeekCachedNames(t *testing.T) {
+	isolateUserDirs(t)
+	if names, ok := PeekCachedNames(SDKManCandidatesCacheKey); ok {
+		t.Fatalf("got %v without a cache", names)
+	}
+	want := []string{"java", "maven"}
+	entry, err := SaveCache(SDKManCandidatesCacheKey, CacheEntry{Names: want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	InvalidateCache(SDKManCandidatesCacheKey)
+	names, ok := PeekCachedNames(SDKManCandidatesCacheKey)
+	if !ok || !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, %v, want the expired cache %v", names, ok, entry.Names)
+	}
+}
+
+func TestInvalidateCacheReplacesTheFile(t *testing.T) {
+	isolateUserDirs(t)
+	if _, err := SaveCache("java", CacheEntry{Candidates: []Candidate{{Identifier: "21.0.2-tem"}}}); err != nil {
+		t.Fatal(err)
+	}
+	InvalidateCache("java")
+	entry, ok := LoadCache("java")
+	if !ok || !entry.FetchedAt.IsZero() || len(entry.Candidates) != 1 {
+		t.Errorf("got %+v, %v, want the expired list", entry, ok)
+	}
+	path, err := cacheFilePath("java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leftovers, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leftovers) > 0 {
+		t.Errorf("temp files left behind: %v", leftovers)
+	}
+}
+
+// BenchmarkLoadCachedLists times reading the settings, the cached candidate
+// list and every cached version list, sorted, for 60 candidates. It covers
+// only the data the menus are built from, not building the menus or the
+// load workers, which need a running tray.
+func BenchmarkLoadCachedLists(b *testing.B) {
+	dir := b.TempDir()
+	b.Setenv("HOME", dir)
+	b.Setenv("XDG_CONFIG_HOME", dir)
+	b.Setenv("XDG_CACHE_HOME", dir)
+	b.Setenv("SDKMAN_DIR", "")
+	b.Setenv("NVM_DIR", "")
+	var names []string
+	for i := 0; i < 60; i++ {
+		name := fmt.Sprintf("candidate%d", i)
+		names = append(names, name)
+		var versions []Candidate
+		for v := 0; v < 100; v++ {
+			versions = append(versions, Candidate{Identifier: fmt.Sprintf("%d.%d.0-tem", v/10, v%10)})
+		}
+		if _, err := SaveCache(SDKManCacheKey(name), CacheEntry{Candidates: versions}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if _, err := SaveCache(SDKManCandidatesCacheKey, CacheEntry{Names: names}); err != nil {
+		b.Fatal(err)
+	}
+	previous := CurrentConfig()
+	b.Cleanup(func() { setConfig(previous) })
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := LoadConfig(); err != nil {
+			b.Fatal(err)
+		}
+		cached, ok := PeekCachedNames(SDKManCandidatesCacheKey)
+		if !ok {
+			b.Fatal("no cached candidates")
+		}
+		for _, name := range cached {
+			if entry, ok := LoadCache(SDKManCacheKey(name)); ok {
+				SortCandidates(entry.Candidates)
+			}
+		}
+	}
+}
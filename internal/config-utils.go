@@ -0,0 +1,386 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+type ProvidersConfig struct {
+	SDKMan bool `json:"sdkman"`
+	NVM    bool `json:"nvm"`
+}
+
+type Config struct {
+	// SDKManDir and NVMDir are only set when the user picked the tool
+	// directories; empty ones follow SDKMAN_DIR and NVM_DIR, see
+	// SDKManDir() and NVMDir().
+	SDKManDir     string            `json:"sdkman_dir"`
+	NVMDir        string            `json:"nvm_dir"`
+	Providers     ProvidersConfig   `json:"providers"`
+	Candidates    []string          `json:"candidates"`
+	Notifications bool              `json:"notifications"`
+	AutoInstall   bool              `json:"auto_install"`
+	Favorites     []string          `json:"favorites"`
+	Filters       map[string]string `json:"filters"`
+	// ShellIntegration is the user's answer to managing rc files: ask, yes
+	// or no.
+	ShellIntegration string `json:"shell_integration"`
+	// PromptMissingVersions lets the shell hook ask the tray to install
+	// versions a project pins but that are not installed.
+	PromptMissingVersions bool `json:"prompt_missing_versions"`
+	// Bootstrap says where SDKMan and NVM are installed from when missing.
+	Bootstrap BootstrapConfig `json:"bootstrap"`
+	// Retention holds the cleanup policy per tool, e.g. "java" or "node";
+	// tools without one are never pruned.
+	Retention map[string]RetentionPolicy `json:"retention"`
+	// RetentionSchedule is off, notify or auto: whether the daily check only
+	// reports what the policy would remove or removes it.
+	RetentionSchedule string `json:"retention_schedule"`
+	// Projects are directories whose .sdkmanrc and .nvmrc versions are kept.
+	Projects []string `json:"projects"`
+	// StaleAfterDays is how long a version may go unused before it is
+	// listed under Stale Versions.
+	StaleAfterDays int `json:"stale_after_days"`
+	// UpdateCheckHours is how often to look for new releases in the
+	// background; 0 turns the check off.
+	UpdateCheckHours int `json:"update_check_hours"`
+	// ReinstallGlobalPackages carries the global npm packages of the default
+	// Node version over to newly installed and upgraded ones.
+	ReinstallGlobalPackages bool `json:"reinstall_global_packages"`
+	// EOLURL optionally points at a newer copy of the bundled end of life
+	// dataset, refreshed daily.
+	EOLURL string `json:"eol_url"`
+	// EOLWarningDays is how long before its end of life an installed version
+	// is marked in the menu.
+	EOLWarningDays int `json:"eol_warning_days"`
+
+	// sdkmanDir and nvmDir are the tool directories in use, resolved when
+	// the config becomes current and never saved.
+	sdkmanDir string
+	nvmDir    string
+}
+
+const (
+	FilterAll       = "all"
+	FilterInstalled = "installed"
+	FilterLTS       = "lts"
+)
+
+var (
+	config         = resolveDirs(DefaultConfig())
+	configMu       sync.RWMutex
+	configUpdateMu sync.Mutex
+	// configLoadErr is why the config file could not be loaded; while it is
+	// set the app runs on defaults and UpdateConfig does not save them over
+	// the user's file.
+	configLoadErr error
+
+	// inheritedSDKManDir and inheritedNVMDir are SDKMAN_DIR and NVM_DIR as
+	// the app was started with, before setConfig exports its own.
+	inheritedSDKManDir = os.Getenv("SDKMAN_DIR")
+	inheritedNVMDir    = os.Getenv("NVM_DIR")
+)
+
+// DefaultConfig leaves the tool directories empty, so they keep following
+// the environment rather than being pinned to where they were on first run.
+func DefaultConfig() Config {
+	return Config{
+		Providers:               ProvidersConfig{SDKMan: true, NVM: true},
+		Notifications:           true,
+		AutoInstall:             true,
+		ShellIntegration:        ShellIntegrationAsk,
+		PromptMissingVersions:   true,
+		RetentionSchedule:       RetentionOff,
+		StaleAfterDays:          DefaultStaleAfterDays,
+		UpdateCheckHours:        DefaultUpdateCheckHours,
+		ReinstallGlobalPackages: true,
+		EOLWarningDays:          DefaultEOLWarningDays,
+		Bootstrap: BootstrapConfig{
+			SDKMan: InstallerConfig{Source: DefaultSDKManInstaller, Version: DefaultSDKManVersion, SHA256: SDKManInstallerSHA256[DefaultSDKManVersion]},
+			NVM:    InstallerConfig{Source: DefaultNVMInstaller, Version: DefaultNVMVersion, SHA256: NVMInstallerSHA256[DefaultNVMVersion]},
+		},
+	}
+}
+
+// resolveDirs fills in the tool directories in use: the configured ones,
+// else SDKMAN_DIR and NVM_DIR from the environment, else the locations the
+// official installers pick.
+func resolveDirs(cfg Config) Config {
+	homeDir, _ := os.UserHomeDir()
+	cfg.sdkmanDir = firstNonEmpty(cfg.SDKManDir, inheritedSDKManDir, filepath.Join(homeDir, ".sdkman"))
+	cfg.nvmDir = firstNonEmpty(cfg.NVMDir, inheritedNVMDir, filepath.Join(homeDir, ".nvm"))
+	return cfg
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func ConfigFilePath() (string, error) {
+	dir, err := AppConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// LoadConfig reads the config file, writing the defaults first if it does
+// not exist yet. Missing keys keep their default values. The loaded config
+// only becomes current once it validates.
+func LoadConfig() (Config, error) {
+	cfg, err := loadConfig()
+	configMu.Lock()
+	configLoadErr = err
+	configMu.Unlock()
+	return cfg, err
+}
+
+func loadConfig() (Config, error) {
+	path, err := ConfigFilePath()
+	if err != nil {
+		return CurrentConfig(), err
+	}
+	cfg := DefaultConfig()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, SaveConfig(cfg)
+	}
+	if err != nil {
+		return CurrentConfig(), err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return CurrentConfig(), fmt.Errorf("parsing %s: %w", path, err)
+	}
+	cfg.SDKManDir = expandHome(cfg.SDKManDir)
+	cfg.NVMDir = expandHome(cfg.NVMDir)
+	for i, project := range cfg.Projects {
+		cfg.Projects[i] = expandHome(project)
+	}
+	if err := cfg.Validate(); err != nil {
+		return CurrentConfig(), fmt.Errorf("invalid %s: %w", path, err)
+	}
+	setConfig(cfg)
+	return cfg, nil
+}
+
+// SaveConfig writes cfg and makes it the current config.
+func SaveConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	path, err := ConfigFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	setConfig(cfg)
+	return nil
+}
+
+func (c Config) Validate() error {
+	if c.SDKManDir != "" && !filepath.IsAbs(c.SDKManDir) {
+		return fmt.Errorf("sdkman_dir must be empty or an absolute path, got %q", c.SDKManDir)
+	}
+	if c.NVMDir != "" && !filepath.IsAbs(c.NVMDir) {
+		return fmt.Errorf("nvm_dir must be empty or an absolute path, got %q", c.NVMDir)
+	}
+	for _, name := range append(append([]string{}, c.Candidates...), c.Favorites...) {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, " /;&|") {
+			return fmt.Errorf("invalid candidate name %q", name)
+		}
+	}
+	switch c.ShellIntegration {
+	case ShellIntegrationAsk, ShellIntegrationYes, ShellIntegrationNo:
+	default:
+		return fmt.Errorf("shell_integration must be ask, yes or no, got %q", c.ShellIntegration)
+	}
+	if err := c.Bootstrap.SDKMan.Validate(); err != nil {
+		return fmt.Errorf("bootstrap.sdkman: %w", err)
+	}
+	if err := c.Bootstrap.NVM.Validate(); err != nil {
+		return fmt.Errorf("bootstrap.nvm: %w", err)
+	}
+	switch c.RetentionSchedule {
+	case RetentionOff, RetentionNotify, RetentionAuto:
+	default:
+		return fmt.Errorf("retention_schedule must be off, notify or auto, got %q", c.RetentionSchedule)
+	}
+	for tool, policy := range c.Retention {
+		if policy.KeepPatches < 1 {
+			return fmt.Errorf("retention for %s must keep at least 1 patch per line, got %d", tool, policy.KeepPatches)
+		}
+	}
+	if c.StaleAfterDays < 1 {
+		return fmt.Errorf("stale_after_days must be at least 1, got %d", c.StaleAfterDays)
+	}
+	if c.UpdateCheckHours < 0 {
+		return fmt.Errorf("update_check_hours must not be negative, got %d", c.UpdateCheckHours)
+	}
+	if c.EOLWarningDays < 0 {
+		return fmt.Errorf("eol_warning_days must not be negative, got %d", c.EOLWarningDays)
+	}
+	if c.EOLURL != "" && !strings.HasPrefix(c.EOLURL, "https://") && !strings.HasPrefix(c.EOLURL, "http://") {
+		return fmt.Errorf("eol_url must be an http or https URL, got %q", c.EOLURL)
+	}
+	for _, project := range c.Projects {
+		if !filepath.IsAbs(project) {
+			return fmt.Errorf("projects must be absolute paths, got %q", project)
+		}
+	}
+	for name, filter := range c.Filters {
+		switch filter {
+		case FilterAll, FilterInstalled, FilterLTS:
+		default:
+			return fmt.Errorf("invalid filter %q for %s, expected all, installed or lts", filter, name)
+		}
+	}
+	return nil
+}
+
+func (c Config) IsFavorite(name string) bool {
+	for _, favorite := range c.Favorites {
+		if strings.EqualFold(favorite, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) SetFavorite(name string, favorite bool) {
+	var favorites []string
+	for _, f := range c.Favorites {
+		if !strings.EqualFold(f, name) {
+			favorites = append(favorites, f)
+		}
+	}
+	if favorite {
+		favorites = append(favorites, name)
+	}
+	c.Favorites = favorites
+}
+
+func (c Config) Filter(name string) string {
+	if filter, ok := c.Filters[strings.ToLower(name)]; ok {
+		return filter
+	}
+	return FilterAll
+}
+
+func (c *Config) SetFilter(name string, filter string) {
+	if c.Filters == nil {
+		c.Filters = make(map[string]string)
+	}
+	if filter == FilterAll {
+		delete(c.Filters, strings.ToLower(name))
+		return
+	}
+	c.Filters[strings.ToLower(name)] = filter
+}
+
+// CandidateEnabled reports whether name should get a menu; an empty
+// candidates list enables all of them.
+func (c Config) CandidateEnabled(name string) bool {
+	if len(c.Candidates) == 0 {
+		return true
+	}
+	for _, candidate := range c.Candidates {
+		if strings.EqualFold(candidate, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateConfig applies change to the current config and saves it, so menu
+// toggles can persist single settings without racing each other. Nothing is
+// saved while the config file failed to load, since that would replace the
+// user's settings with the defaults.
+func UpdateConfig(change func(cfg *Config)) error {
+	configUpdateMu.Lock()
+	defer configUpdateMu.Unlock()
+	configMu.Lock()
+	if configLoadErr != nil {
+		err := configLoadErr
+		configMu.Unlock()
+		return fmt.Errorf("not saving settings until the settings file loads: %w", err)
+	}
+	cfg := config
+	cfg.Candidates = append([]string{}, config.Candidates...)
+	cfg.Favorites = append([]string{}, config.Favorites...)
+	cfg.Filters = make(map[string]string)
+	for k, v := range config.Filters {
+		cfg.Filters[k] = v
+	}
+	cfg.Retention = make(map[string]RetentionPolicy)
+	for k, v := range config.Retention {
+		cfg.Retention[k] = v
+	}
+	cfg.Projects = append([]string{}, config.Projects...)
+	configMu.Unlock()
+	change(&cfg)
+	return SaveConfig(cfg)
+}
+
+func CurrentConfig() Config {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return config
+}
+
+// setConfig resolves the tool directories and exports them, so
+// sdkman-init.sh, nvm.sh and their installers started from the app use the
+// same locations.
+func setConfig(cfg Config) {
+	cfg = resolveDirs(cfg)
+	configMu.Lock()
+	config = cfg
+	configMu.Unlock()
+	os.Setenv("SDKMAN_DIR", cfg.sdkmanDir)
+	os.Setenv("NVM_DIR", cfg.nvmDir)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(path, "~"))
+}
+
+// OpenInEditor opens path in the user's default text editor rather than the
+// application registered for its extension.
+func OpenInEditor(path string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", "-t", path)
+	case "windows":
+		cmd = exec.Command("notepad", path)
+	case "linux":
+		cmd = exec.Command("xdg-open", path)
+	default:
+		return fmt.Errorf("unsupported platform")
+	}
+	return cmd.Start()
+}
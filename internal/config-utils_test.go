@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigKeepsToolDirsUnset(t *testing.T) {
+	isolateUserDirs(t)
+	useConfig(t, DefaultConfig())
+	home, _ := os.UserHomeDir()
+	previousSDKMan, previousNVM := inheritedSDKManDir, inheritedNVMDir
+	t.Cleanup(func() { inheritedSDKManDir, inheritedNVMDir = previousSDKMan, previousNVM })
+	inheritedSDKManDir, inheritedNVMDir = "/opt/sdkman", ""
+
+	if _, err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	path, err := ConfigFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]any
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved["sdkman_dir"] != "" || saved["nvm_dir"] != "" {
+		t.Errorf("first run saved tool directories: sdkman_dir %v, nvm_dir %v", saved["sdkman_dir"], saved["nvm_dir"])
+	}
+	if got := SDKManDir(); got != "/opt/sdkman" {
+		t.Errorf("SDKManDir() = %q, want SDKMAN_DIR", got)
+	}
+	if got, want := NVMDir(), filepath.Join(home, ".nvm"); got != want {
+		t.Errorf("NVMDir() = %q, want %q", got, want)
+	}
+
+	// A later environment is followed, since nothing was pinned.
+	inheritedSDKManDir = "/srv/sdkman"
+	if _, err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if got := SDKManDir(); got != "/srv/sdkman" {
+		t.Errorf("SDKManDir() = %q after SDKMAN_DIR changed", got)
+	}
+
+	if err := UpdateConfig(func(cfg *Config) { cfg.NVMDir = "/data/nvm" }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if got := NVMDir(); got != "/data/nvm" {
+		t.Errorf("NVMDir() = %q, want the configured directory", got)
+	}
+	if got := os.Getenv("NVM_DIR"); got != "/data/nvm" {
+		t.Errorf("NVM_DIR = %q, want the configured directory exported", got)
+	}
+	if got := CurrentConfig().SDKManDir; got != "" {
+		t.Errorf("sdkman_dir = %q after saving, want it left empty", got)
+	}
+}
+
+func TestUpdateConfigKeepsUnloadableFile(t *testing.T) {
+	isolateUserDirs(t)
+	useConfig(t, DefaultConfig())
+	t.Cleanup(func() { configLoadErr = nil })
+	path, err := ConfigFilePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := []byte(`{"favorites": ["java"],}`)
+	if err := os.WriteFile(path, broken, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); err == nil {
+		t.Fatal("expected the broken file to fail loading")
+	}
+	if err := UpdateConfig(func(cfg *Config) { cfg.SetFavorite("node", true) }); err == nil {
+		t.Error("UpdateConfig saved over a file that failed to load")
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(broken) {
+		t.Errorf("settings file was rewritten: %s", data)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"candidates": ["java"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	before := CurrentConfig()
+	if err := UpdateConfig(func(cfg *Config) { cfg.Candidates[0] = "maven" }); err != nil {
+		t.Fatal(err)
+	}
+	if before.Candidates[0] != "java" {
+		t.Errorf("UpdateConfig changed the candidates of an earlier config to %v", before.Candidates)
+	}
+	if got := CurrentConfig().Candidates; len(got) != 1 || got[0] != "maven" {
+		t.Errorf("candidates = %v after the update", got)
+	}
+}
@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// PinnablePackageManagers are the package managers corepack provides.
+var PinnablePackageManagers = []string{"pnpm", "yarn"}
+
+var packageSpecPattern = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z.+-]*$`)
+
+// PackageManagers is the npm, corepack, pnpm and yarn state of one installed
+// Node version. Empty versions are not installed. With corepack enabled the
+// pnpm and yarn versions are corepack's pinned ones, which every Node
+// version with corepack enabled shares; projects that set packageManager in
+// package.json still get the version they ask for.
+type PackageManagers struct {
+	NPM             string
+	Corepack        string
+	CorepackEnabled bool
+	Versions        map[string]string
+}
+
+// NodePackageManagers reads the state from the Node install and corepack's
+// cache without running anything, so it is cheap enough for menu updates.
+func NodePackageManagers(version string) PackageManagers {
+	home := LocalNodeHome(version)
+	modules := filepath.Join(home, "lib", "node_modules")
+	state := PackageManagers{
+		NPM:      packageVersion(filepath.Join(modules, "npm")),
+		Corepack: packageVersion(filepath.Join(modules, "corepack")),
+		Versions: make(map[string]string),
+	}
+	for _, name := range PinnablePackageManagers {
+		if target, err := os.Readlink(filepath.Join(home, "bin", name)); err == nil && strings.Contains(target, "corepack") {
+			state.CorepackEnabled = true
+		}
+	}
+	if state.CorepackEnabled {
+		if data, err := os.ReadFile(filepath.Join(corepackHome(), "lastKnownGood.json")); err == nil {
+			json.Unmarshal(data, &state.Versions)
+		}
+		return state
+	}
+	for _, name := range PinnablePackageManagers {
+		if v := packageVersion(filepath.Join(modules, name)); v != "" {
+			state.Versions[name] = v
+		}
+	}
+	return state
+}
+
+func packageVersion(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var manifest struct {
+		Version string `json:"version"`
+	}
+	json.Unmarshal(data, &manifest)
+	return manifest.Version
+}
+
+// corepackHome is where corepack keeps its downloads and pins, following
+// corepack's own lookup.
+func corepackHome() string {
+	if home := os.Getenv("COREPACK_HOME"); home != "" {
+		return home
+	}
+	cache := os.Getenv("XDG_CACHE_HOME")
+	if cache == "" {
+		cache = os.Getenv("LOCALAPPDATA")
+	}
+	if cache == "" {
+		homeDir, _ := os.UserHomeDir()
+		if runtime.GOOS == "windows" {
+			cache = filepath.Join(homeDir, "AppData", "Local")
+		} else {
+			cache = filepath.Join(homeDir, ".cache")
+		}
+	}
+	return filepath.Join(cache, "node", "corepack")
+}
+
+// nodeExec runs command with version's node and npm first on the PATH.
+func nodeExec(version string, command string) (string, error) {
+	return CommandExecCombined([]string{nvmEnv() + "&& nvm use --silent " + shellQuote(version) + " && " + command})
+}
+
+func validPackageSpec(spec string) error {
+	if !packageSpecPattern.MatchString(spec) {
+		return fmt.Errorf("invalid version %q", spec)
+	}
+	return nil
+}
+
+// UpgradeNPM installs npm@spec into version. An empty spec installs the
+// latest npm that supports that Node version.
+func UpgradeNPM(version string, spec string) (string, error) {
+	slog.Info("upgrading npm", "node", version, "spec", spec)
+	if spec == "" {
+		return nodeExec(version, "nvm install-latest-npm")
+	}
+	if err := validPackageSpec(spec); err != nil {
+		return "", err
+	}
+	return nodeExec(version, "npm install -g "+shellQuote("npm@"+spec))
+}
+
+// SetCorepack turns corepack's pnpm and yarn shims on or off for version.
+// Node versions that do not bundle corepack get it from npm first.
+func SetCorepack(version string, enabled bool) (string, error) {
+	slog.Info("setting corepack", "node", version, "enabled", enabled)
+	if !enabled {
+		return nodeExec(version, "corepack disable")
+	}
+	command := "corepack enable"
+	if NodePackageManagers(version).Corepack == "" {
+		command = "npm install -g corepack && " + command
+	}
+	return nodeExec(version, command)
+}
+
+// PinPackageManager makes name@spec the pnpm or yarn that version runs
+// outside of projects that pin their own. With corepack enabled the pin is
+// corepack's, otherwise the package is installed globally with npm.
+func PinPackageManager(version string, name string, spec string) (string, error) {
+	if name != "pnpm" && name != "yarn" {
+		return "", fmt.Errorf("unknown package manager %q", name)
+	}
+	if err := validPackageSpec(spec); err != nil {
+		return "", err
+	}
+	pkg := shellQuote(name + "@" + spec)
+	slog.Info("pinning package manager", "node", version, "package", name+"@"+spec)
+	if NodePackageManagers(version).CorepackEnabled {
+		// corepack install arrived in corepack 0.20; prepare is the older way.
+		return nodeExec(version, "{ corepack install --global "+pkg+" || corepack prepare "+pkg+" --activate; }")
+	}
+	return nodeExec(version, "npm install -g "+pkg)
+}
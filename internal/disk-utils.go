@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	diskScanWorkers = 4
+	// diskUsageTTL bounds how long a size is trusted, for changes deeper
+	// than the directories sizeFingerprint looks at.
+	diskUsageTTL = 24 * time.Hour
+)
+
+// VersionUsage is the space one installed version takes up.
+type VersionUsage struct {
+	Tool    string
+	Version string
+	Path    string
+	Size    int64
+	Use     bool
+}
+
+// diskUsageEntry caches a directory's size; it is reused while the
+// directory's fingerprint is unchanged and the scan is not older than
+// diskUsageTTL.
+type diskUsageEntry struct {
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+var diskUsageMu sync.Mutex
+
+// ScanDiskUsage sizes every installed SDKMan candidate version and Node
+// version, largest first. Sizes are computed concurrently and cached, so
+// only versions whose directory changed are walked again. Versions linked
+// in from elsewhere with `sdk install <candidate> <version> <path>` are
+// skipped since removing them frees nothing.
+func ScanDiskUsage() ([]VersionUsage, error) {
+	var usages []VersionUsage
+	names, err := LocalCandidateNames()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, name := range names {
+		versions, err := LocalCandidateVersions(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			if !v.Custom {
+				usages = append(usages, VersionUsage{Tool: name, Version: v.Identifier, Path: LocalCandidateHome(name, v.Identifier), Use: v.Use})
+			}
+		}
+	}
+	nodeVersions, err := LocalNodeVersions()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, v := range nodeVersions {
+		usages = append(usages, VersionUsage{Tool: NodeWatchKey, Version: v.Identifier, Path: LocalNodeHome(v.Identifier), Use: v.Use})
+	}
+
+	diskUsageMu.Lock()
+	defer diskUsageMu.Unlock()
+	cache := loadDiskUsageCache()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, diskScanWorkers)
+	for i := range usages {
+		modTime, err := sizeFingerprint(usages[i].Path)
+		if err != nil {
+			continue
+		}
+		if entry, ok := cache[usages[i].Path]; ok && entry.ModTime.Equal(modTime) && time.Since(entry.ScannedAt) < diskUsageTTL {
+			usages[i].Size = entry.Size
+			continue
+		}
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(usage *VersionUsage, modTime time.Time) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			usage.Size = dirSize(usage.Path)
+			mu.Lock()
+			cache[usage.Path] = diskUsageEntry{Size: usage.Size, ModTime: modTime, ScannedAt: time.Now()}
+			mu.Unlock()
+		}(&usages[i], modTime)
+	}
+	wg.Wait()
+
+	current := make(map[string]diskUsageEntry)
+	for _, usage := range usages {
+		if entry, ok := cache[usage.Path]; ok {
+			current[usage.Path] = entry
+		}
+	}
+	saveDiskUsageCache(current)
+	sort.SliceStable(usages, func(i, j int) bool { return usages[i].Size > usages[j].Size })
+	return usages, nil
+}
+
+// sizeFingerprint is the latest modification time of path, its direct
+// subdirectories and the packages in lib/node_modules, where `npm install
+// -g` adds to a Node version without touching the version directory.
+func sizeFingerprint(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	latest := info.ModTime()
+	for _, dir := range []string{path, filepath.Join(path, "lib"), filepath.Join(path, "lib", "node_modules")} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			if info, err := entry.Info(); err == nil && info.ModTime().After(latest) {
+				latest = info.ModTime()
+			}
+		}
+	}
+	return latest, nil
+}
+
+// dirSize adds up the regular files under path without following symlinks.
+func dirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+func diskUsageCachePath() (string, error) {
+	dir, err := AppCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "disk-usage.json"), nil
+}
+
+func loadDiskUsageCache() map[string]diskUsageEntry {
+	cache := make(map[string]diskUsageEntry)
+	path, err := diskUsageCachePath()
+	if err != nil {
+		return cache
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+func saveDiskUsageCache(cache map[string]diskUsageEntry) {
+	path, err := diskUsageCachePath()
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err == nil {
+		os.Rename(tmp, path)
+	}
+}
+
+// FormatSize renders bytes the way Finder and file managers do, e.g. 312 MB.
+func FormatSize(size int64) string {
+	const unit = 1000
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "kMGTPE"[exp])
+}
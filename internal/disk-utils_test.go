@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func nodeUsage(t *testing.T, version string) VersionUsage {
+	t.Helper()
+	usages, err := ScanDiskUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, usage := range usages {
+		if usage.Tool == NodeWatchKey && usage.Version == version {
+			return usage
+		}
+	}
+	t.Fatalf("node %s not in %v", version, usages)
+	return VersionUsage{}
+}
+
+func writeSizedFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanDiskUsageNoticesGlobalPackages(t *testing.T) {
+	isolateUserDirs(t)
+	useToolDirs(t)
+	home := LocalNodeHome("v20.11.1")
+	writeSizedFile(t, filepath.Join(home, "bin", "node"), 1000)
+	writeSizedFile(t, filepath.Join(home, "lib", "node_modules", "npm", "package.json"), 100)
+	if got := nodeUsage(t, "v20.11.1").Size; got != 1100 {
+		t.Fatalf("size %d, want 1100", got)
+	}
+
+	// A global install only touches lib/node_modules.
+	writeSizedFile(t, filepath.Join(home, "lib", "node_modules", "typescript", "lib", "tsc.js"), 500)
+	if got := nodeUsage(t, "v20.11.1").Size; got != 1600 {
+		t.Errorf("size %d after npm install -g, want 1600", got)
+	}
+	writeSizedFile(t, filepath.Join(home, "lib", "node_modules", "@angular", "cli", "index.js"), 400)
+	if got := nodeUsage(t, "v20.11.1").Size; got != 2000 {
+		t.Errorf("size %d after a scoped install, want 2000", got)
+	}
+}
+
+func TestScanDiskUsageRescansAfterTTL(t *testing.T) {
+	isolateUserDirs(t)
+	useToolDirs(t)
+	home := LocalNodeHome("v20.11.1")
+	writeSizedFile(t, filepath.Join(home, "share", "doc", "node", "README.md"), 100)
+	if got := nodeUsage(t, "v20.11.1").Size; got != 100 {
+		t.Fatalf("size %d, want 100", got)
+	}
+
+	// Deep changes keep the fingerprint, so the cached size stays until
+	// the entry expires.
+	writeSizedFile(t, filepath.Join(home, "share", "doc", "node", "CHANGELOG.md"), 300)
+	if got := nodeUsage(t, "v20.11.1").Size; got != 100 {
+		t.Fatalf("size %d within the TTL, want the cached 100", got)
+	}
+	cache := loadDiskUsageCache()
+	entry := cache[home]
+	entry.ScannedAt = time.Now().Add(-diskUsageTTL - time.Minute)
+	cache[home] = entry
+	saveDiskUsageCache(cache)
+	if got := nodeUsage(t, "v20.11.1").Size; got != 400 {
+		t.Errorf("size %d after the TTL, want 400", got)
+	}
+}
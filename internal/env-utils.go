@@ -0,0 +1,235 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	SDKManRCFile = ".sdkmanrc"
+	NVMRCFile    = ".nvmrc"
+
+	// The hook remembers what it changed in these variables so leaving a
+	// project directory can undo it.
+	envPathsVar = "SDK_UI_GO_PATHS"
+	envHomesVar = "SDK_UI_GO_HOMES"
+)
+
+// ProjectVersion is a version pinned by a .sdkmanrc or .nvmrc file.
+type ProjectVersion struct {
+	Tool    string
+	Version string
+	File    string
+}
+
+// ProjectEnv is what a shell in a directory should export: the bin
+// directories to put in front of PATH, the <TOOL>_HOME variables to set and
+// the pinned versions that are not installed.
+type ProjectEnv struct {
+	Paths   []string
+	Homes   map[string]string
+	Missing []ProjectVersion
+}
+
+// FindProjectVersions reads the nearest .sdkmanrc and the nearest .nvmrc
+// above dir; each is looked up on its own, like `sdk env` and `nvm use` do.
+func FindProjectVersions(dir string) ([]ProjectVersion, error) {
+	var versions []ProjectVersion
+	if path := findUp(dir, SDKManRCFile); path != "" {
+		pinned, err := parseSDKManRC(path)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, pinned...)
+	}
+	if path := findUp(dir, NVMRCFile); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if version := strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0]); version != "" {
+			versions = append(versions, ProjectVersion{Tool: NodeWatchKey, Version: version, File: path})
+		}
+	}
+	return versions, nil
+}
+
+func findUp(dir string, name string) string {
+	for {
+		path := filepath.Join(dir, name)
+		if FileExists(path) {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseSDKManRC reads candidate=version lines, skipping comments and blanks
+// as `sdk env` does.
+func parseSDKManRC(path string) ([]ProjectVersion, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var versions []ProjectVersion
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tool, version, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		versions = append(versions, ProjectVersion{Tool: strings.TrimSpace(tool), Version: strings.TrimSpace(version), File: path})
+	}
+	return versions, scanner.Err()
+}
+
+// ResolveProjectEnv maps the versions pinned for dir to local installs. It
+// never touches the network, so it is cheap enough to run on every cd.
+func ResolveProjectEnv(dir string) (ProjectEnv, error) {
+	env := ProjectEnv{Homes: make(map[string]string)}
+	versions, err := FindProjectVersions(dir)
+	if err != nil {
+		return env, err
+	}
+	for _, v := range versions {
+		home := projectVersionHome(v)
+		if home == "" {
+			env.Missing = append(env.Missing, v)
+			continue
+		}
+		env.Paths = append(env.Paths, filepath.Join(home, "bin"))
+		if v.Tool != NodeWatchKey {
+			env.Homes[homeVar(v.Tool)] = home
+		}
+	}
+	return env, nil
+}
+
+func projectVersionHome(v ProjectVersion) string {
+	if v.Tool != NodeWatchKey {
+		home := LocalCandidateHome(v.Tool, v.Version)
+		if !FileExists(home) {
+			return ""
+		}
+		return home
+	}
+	installed, err := LocalNodeVersions()
+	if err != nil {
+		return ""
+	}
+	var names []string
+	for _, c := range installed {
+		names = append(names, c.Identifier)
+	}
+	version := ResolveNodeVersion(v.Version, names)
+	if version == "" {
+		return ""
+	}
+	return LocalNodeHome(version)
+}
+
+// homeVar is the variable sdkman-init.sh exports for a candidate, e.g.
+// JAVA_HOME or GRADLE_HOME.
+func homeVar(tool string) string {
+	return strings.ToUpper(strings.ReplaceAll(tool, "-", "_")) + "_HOME"
+}
+
+// ShellExports turns env into POSIX shell statements. Whatever the previous
+// call added to PATH is taken out again, and homes it set that env no longer
+// needs fall back to the candidate's current version.
+func ShellExports(env ProjectEnv) string {
+	separator := string(os.PathListSeparator)
+	previous := make(map[string]bool)
+	for _, path := range filepath.SplitList(os.Getenv(envPathsVar)) {
+		previous[path] = true
+	}
+	path := append([]string{}, env.Paths...)
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if !previous[entry] && !containsString(env.Paths, entry) {
+			path = append(path, entry)
+		}
+	}
+
+	var lines []string
+	lines = append(lines, "export PATH="+shellQuote(strings.Join(path, separator)))
+	lines = append(lines, "export "+envPathsVar+"="+shellQuote(strings.Join(env.Paths, separator)))
+	for _, name := range strings.Fields(os.Getenv(envHomesVar)) {
+		if _, ok := env.Homes[name]; ok {
+			continue
+		}
+		tool := strings.ToLower(strings.TrimSuffix(name, "_HOME"))
+		if current := filepath.Join(SDKManCandidatesDir(), tool, "current"); FileExists(current) {
+			lines = append(lines, "export "+name+"="+shellQuote(current))
+		} else {
+			lines = append(lines, "unset "+name)
+		}
+	}
+	var homes []string
+	for name := range env.Homes {
+		homes = append(homes, name)
+	}
+	sort.Strings(homes)
+	for _, name := range homes {
+		lines = append(lines, "export "+name+"="+shellQuote(env.Homes[name]))
+	}
+	lines = append(lines, "export "+envHomesVar+"="+shellQuote(strings.Join(homes, " ")))
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ShellHook is the script `eval "$(sdkuigo hook <shell>)"` installs: it runs
+// `sdkuigo env` whenever the shell changes directory.
+func ShellHook(shell string, executable string) (string, error) {
+	command := shellQuote(executable) + " env"
+	switch shell {
+	case "bash":
+		return `_sdk_ui_go_hook() {
+  local status=$?
+  if [ "$PWD" != "$_SDK_UI_GO_PWD" ]; then
+    _SDK_UI_GO_PWD="$PWD"
+    eval "$(` + command + `)"
+  fi
+  return $status
+}
+case ";${PROMPT_COMMAND:-};" in
+  *";_sdk_ui_go_hook;"*) ;;
+  *) PROMPT_COMMAND="_sdk_ui_go_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`, nil
+	case "zsh":
+		return `_sdk_ui_go_hook() {
+  eval "$(` + command + `)"
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _sdk_ui_go_hook
+_sdk_ui_go_hook
+`, nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q, expected bash or zsh", shell)
+	}
+}
@@ -0,0 +1,263 @@
+package internal
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultEOLWarningDays = 90
+
+	eolDateLayout = "2006-01-02"
+)
+
+const (
+	EOLNone = ""
+	EOLSoon = "soon"
+	EOLPast = "past"
+)
+
+// bundledEOL is the dataset shipped with the app; eol_url can point at a
+// newer copy in the same format.
+//
+//go:embed eol.json
+var bundledEOL []byte
+
+// EOLData holds the last day of support per Node major and per Java vendor
+// and major, e.g. Java["tem"]["17"]. Dates are YYYY-MM-DD.
+type EOLData struct {
+	Updated string                       `json:"updated"`
+	Node    map[string]string            `json:"node"`
+	Java    map[string]map[string]string `json:"java"`
+}
+
+// EOLFinding is an installed version past the end of its support.
+type EOLFinding struct {
+	Tool    string
+	Version string
+	EndDate time.Time
+}
+
+func (f EOLFinding) String() string {
+	return f.Tool + " " + f.Version + " (" + f.EndDate.Format(eolDateLayout) + ")"
+}
+
+var (
+	eolData     EOLData
+	eolDataOnce sync.Once
+	eolDataMu   sync.RWMutex
+)
+
+// CurrentEOLData is the refreshed dataset from the cache when there is one,
+// the bundled one otherwise.
+func CurrentEOLData() EOLData {
+	eolDataOnce.Do(func() {
+		data, err := ParseEOLData(bundledEOL)
+		if err != nil {
+			slog.Error("parsing the bundled EOL data", "err", err)
+		}
+		if path, err := eolCachePath(); err == nil {
+			if cached, err := os.ReadFile(path); err == nil {
+				if refreshed, err := ParseEOLData(cached); err == nil && refreshed.Updated >= data.Updated {
+					data = refreshed
+				}
+			}
+		}
+		eolDataMu.Lock()
+		eolData = data
+		eolDataMu.Unlock()
+	})
+	eolDataMu.RLock()
+	defer eolDataMu.RUnlock()
+	return eolData
+}
+
+// ParseEOLData reads a dataset and checks every date in it.
+func ParseEOLData(raw []byte) (EOLData, error) {
+	var data EOLData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return data, err
+	}
+	dates := []map[string]string{data.Node}
+	for _, lines := range data.Java {
+		dates = append(dates, lines)
+	}
+	for _, lines := range dates {
+		for major, date := range lines {
+			if _, err := strconv.Atoi(major); err != nil {
+				return data, fmt.Errorf("invalid major version %q", major)
+			}
+			if _, err := time.Parse(eolDateLayout, date); err != nil {
+				return data, fmt.Errorf("invalid end of life date %q for %s", date, major)
+			}
+		}
+	}
+	return data, nil
+}
+
+// RefreshEOLData downloads the dataset from url, keeps it in the cache and
+// makes it current.
+func RefreshEOLData(url string) error {
+	raw, err := httpGet(url)
+	if err != nil {
+		return err
+	}
+	data, err := ParseEOLData(raw)
+	if err != nil {
+		return fmt.Errorf("parsing EOL data from %s: %w", url, err)
+	}
+	path, err := eolCachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, raw, 0644); err != nil {
+		return err
+	}
+	CurrentEOLData()
+	eolDataMu.Lock()
+	eolData = data
+	eolDataMu.Unlock()
+	slog.Info("refreshed EOL data", "url", url, "updated", data.Updated)
+	return nil
+}
+
+func eolCachePath() (string, error) {
+	dir, err := AppCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "eol.json"), nil
+}
+
+// EndOfLife is the last day of support for an identifier such as
+// 17.0.11-tem or v18.20.3. Java builds are looked up by the Java release
+// they are built on (see JavaVersion), and lines without vendor data that
+// are not LTS end with the next feature release.
+func (d EOLData) EndOfLife(tool string, identifier string) (time.Time, bool) {
+	major := MajorVersion(identifier)
+	if strings.EqualFold(tool, "java") {
+		major = JavaVersion(identifier)
+	}
+	if major == 0 {
+		return time.Time{}, false
+	}
+	var date string
+	switch {
+	case tool == NodeWatchKey:
+		date = d.Node[strconv.Itoa(major)]
+	case strings.EqualFold(tool, "java"):
+		if i := strings.LastIndex(identifier, "-"); i >= 0 {
+			date = d.Java[identifier[i+1:]][strconv.Itoa(major)]
+		}
+		if date == "" && !IsJavaLTS(identifier) && major >= 9 {
+			return javaFeatureRelease(major + 1), true
+		}
+	}
+	end, err := time.Parse(eolDateLayout, date)
+	return end, err == nil
+}
+
+// javaFeatureRelease is roughly when a Java feature release came out: every
+// March and September since Java 10 in March 2018.
+func javaFeatureRelease(major int) time.Time {
+	month := time.March
+	if (major-10)%2 != 0 {
+		month = time.September
+	}
+	return time.Date(2018+(major-10)/2, month, 15, 0, 0, 0, 0, time.UTC)
+}
+
+// Status tells whether identifier is past its end of life, or reaches it
+// within warn of now.
+func (d EOLData) Status(tool string, identifier string, now time.Time, warn time.Duration) (string, time.Time) {
+	end, ok := d.EndOfLife(tool, identifier)
+	switch {
+	case !ok:
+		return EOLNone, end
+	case now.After(end.AddDate(0, 0, 1)):
+		return EOLPast, end
+	case now.Add(warn).After(end):
+		return EOLSoon, end
+	}
+	return EOLNone, end
+}
+
+// NewlyEndedDefaults lists default versions that are past their end of life
+// and were not reported before.
+func NewlyEndedDefaults() ([]EOLFinding, error) {
+	data := CurrentEOLData()
+	now := time.Now()
+	var ended []EOLFinding
+	check := func(tool string, versions []Candidate) {
+		for _, v := range versions {
+			if !v.Use {
+				continue
+			}
+			if status, end := data.Status(tool, v.Identifier, now, 0); status == EOLPast {
+				ended = append(ended, EOLFinding{Tool: tool, Version: v.Identifier, EndDate: end})
+			}
+		}
+	}
+	names, err := LocalCandidateNames()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, name := range names {
+		if versions, err := LocalCandidateVersions(name); err == nil {
+			check(name, versions)
+		}
+	}
+	if versions, err := LocalNodeVersions(); err == nil {
+		check(NodeWatchKey, versions)
+	}
+
+	path, err := eolNotifiedPath()
+	if err != nil {
+		return nil, err
+	}
+	notified := make(map[string]bool)
+	raw, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(raw, &notified); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+	var fresh []EOLFinding
+	for _, finding := range ended {
+		key := finding.Tool + "@" + finding.Version
+		if !notified[key] {
+			notified[key] = true
+			fresh = append(fresh, finding)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i].Tool < fresh[j].Tool })
+	raw, err = json.Marshal(notified)
+	if err != nil {
+		return nil, err
+	}
+	return fresh, os.WriteFile(path, raw, 0644)
+}
+
+func eolNotifiedPath() (string, error) {
+	dir, err := AppCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "eol-notified.json"), nil
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBundledEOLData(t *testing.T) {
+	data, err := ParseEOLData(bundledEOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, vendor := range []string{"tem", "amzn", "zulu", "oracle"} {
+		if data.Java[vendor]["25"] == "" {
+			t.Errorf("no Java 25 end of life for %s", vendor)
+		}
+	}
+}
+
+func TestEndOfLife(t *testing.T) {
+	data, err := ParseEOLData(bundledEOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		tool       string
+		identifier string
+		want       string
+	}{
+		{"java", "17.0.11-tem", "2027-10-31"},
+		{"java", "25.0.1-tem", "2031-09-30"},
+		{"java", "25.0.1-amzn", "2032-10-31"},
+		{"java", "21.0.4.fx-zulu", "2031-09-30"},
+		{"java", "21.0.4.crac-zulu", "2031-09-30"},
+		// Non-LTS lines end with the next feature release.
+		{"java", "22.0.2-oracle", "2024-09-15"},
+		{"java", "24.1.1.r23-nik", "2025-03-15"},
+		// GraalVM and Mandrel builds follow the Java release they are
+		// built on, not their own version.
+		{"java", "23.1.4.r21-mandrel", ""},
+		{"java", "22.3.r17-grl", ""},
+		{"java", "21.0.2-graalce", ""},
+		{NodeWatchKey, "v20.11.1", "2026-04-30"},
+		{NodeWatchKey, "v21.7.3", "2024-06-01"},
+		{"maven", "3.9.9", ""},
+	}
+	for _, tt := range tests {
+		end, ok := data.EndOfLife(tt.tool, tt.identifier)
+		got := ""
+		if ok {
+			got = end.Format(eolDateLayout)
+		}
+		if got != tt.want {
+			t.Errorf("EndOfLife(%s, %s) = %q, want %q", tt.tool, tt.identifier, got, tt.want)
+		}
+	}
+}
+
+func TestEOLStatus(t *testing.T) {
+	data, err := ParseEOLData(bundledEOL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	warn := DefaultEOLWarningDays * 24 * time.Hour
+	tests := []struct {
+		identifier string
+		want       string
+	}{
+		{"21.0.4-oracle", EOLPast},
+		{"8.0.422-tem", EOLSoon},
+		{"21.0.4-tem", EOLNone},
+		{"23.1.4.r21-mandrel", EOLNone},
+		{"22.3.r17-grl", EOLNone},
+	}
+	for _, tt := range tests {
+		if got, _ := data.Status("java", tt.identifier, now, warn); got != tt.want {
+			t.Errorf("Status(%s) = %q, want %q", tt.identifier, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrNotInstalled     = errors.New("not installed")
+	ErrNetwork          = errors.New("network failure")
+	ErrUnknownVersion   = errors.New("unknown version")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrCommandFailed    = errors.New("command failed")
+)
+
+var errorPatterns = []struct {
+	kind     error
+	patterns []string
+}{
+	{ErrPermissionDenied, []string{"permission denied", "operation not permitted", "eacces", "read-only file system"}},
+	{ErrNetwork, []string{"could not resolve host", "failed to connect", "connection refused", "connection timed out", "network is unreachable", "internet not reachable", "offline mode", "ssl_error", "operation timed out"}},
+	{ErrNotInstalled, []string{"command not found", "sdk: not found", "nvm: not found", "is not installed", "not installed.", "no such file or directory"}},
+	{ErrUnknownVersion, []string{"is not available", "not a valid", "version not found", "not found in remote", "n/a: version", "invalid version"}},
+}
+
+// CommandError carries the command and its captured output alongside one of
+// the Err* kinds above, so callers can both branch on the kind and show the
+// raw output to the user.
+type CommandError struct {
+	Kind    error
+	Command string
+	Stdout  string
+	Stderr  string
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Details is the text shown in error dialogs and copied by "Copy details".
+func (e *CommandError) Details() string {
+	var b strings.Builder
+	b.WriteString("Command: " + e.Command + "\n")
+	if e.Err != nil {
+		b.WriteString("Error: " + e.Err.Error() + "\n")
+	}
+	if out := strings.TrimSpace(e.Stderr); out != "" {
+		b.WriteString("\nStderr:\n" + out + "\n")
+	}
+	if out := strings.TrimSpace(e.Stdout); out != "" {
+		b.WriteString("\nOutput:\n" + out + "\n")
+	}
+	return b.String()
+}
+
+func newCommandError(command string, stdout string, stderr string, err error) *CommandError {
+	return &CommandError{
+		Kind:    classifyOutput(stdout + "\n" + stderr),
+		Command: command,
+		Stdout:  stdout,
+		Stderr:  stderr,
+		Err:     err,
+	}
+}
+
+func classifyOutput(output string) error {
+	lower := strings.ToLower(output)
+	for _, p := range errorPatterns {
+		for _, pattern := range p.patterns {
+			if strings.Contains(lower, pattern) {
+				return p.kind
+			}
+		}
+	}
+	return ErrCommandFailed
+}
+
+// ErrorSummary turns an error into a one-line message suitable for a dialog.
+func ErrorSummary(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrPermissionDenied):
+		return "Permission denied. Check the ownership of the SDKMan/NVM directories."
+	case errors.Is(err, ErrNetwork):
+		return "Network failure. Check your internet connection and try again."
+	case errors.Is(err, ErrNotInstalled):
+		return "The tool or version is not installed."
+	case errors.Is(err, ErrUnknownVersion):
+		return "Unknown version. It may have been removed from the remote list."
+	default:
+		return err.Error()
+	}
+}
+
+// ErrorDetails returns the captured output for a CommandError, or the plain
+// error text for anything else.
+func ErrorDetails(err error) string {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Details()
+	}
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
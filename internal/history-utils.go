@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxHistoryOutput = 16 * 1024
+
+var historyMu sync.Mutex
+
+type HistoryEntry struct {
+	User     string    `json:"user"`
+	Host     string    `json:"host"`
+	Provider string    `json:"provider"`
+	Action   string    `json:"action"`
+	Tool     string    `json:"tool"`
+	Version  string    `json:"version,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	ExitCode int       `json:"exit_code"`
+	Output   string    `json:"output,omitempty"`
+}
+
+type HistoryFilter struct {
+	Provider string
+	Action   string
+	Tool     string
+	Since    time.Time
+	Limit    int
+}
+
+func (f HistoryFilter) match(entry HistoryEntry) bool {
+	if f.Provider != "" && !strings.EqualFold(f.Provider, entry.Provider) {
+		return false
+	}
+	if f.Action != "" && !strings.EqualFold(f.Action, entry.Action) {
+		return false
+	}
+	if f.Tool != "" && !strings.EqualFold(f.Tool, entry.Tool) {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Start.Before(f.Since) {
+		return false
+	}
+	return true
+}
+
+func HistoryFilePath() (string, error) {
+	dir, err := AppConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// NewHistoryEntry fills in who ran the operation and where, so entries from a
+// shared machine can be told apart.
+func NewHistoryEntry(op Operation, start time.Time, end time.Time, output string, err error) HistoryEntry {
+	entry := HistoryEntry{
+		Provider: op.Provider,
+		Action:   op.Action,
+		Tool:     op.Tool,
+		Version:  op.Version,
+		Detail:   op.Detail,
+		Start:    start,
+		End:      end,
+		ExitCode: ExitCode(err),
+		Output:   output,
+	}
+	if u, err := user.Current(); err == nil {
+		entry.User = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		entry.Host = host
+	}
+	if len(entry.Output) > maxHistoryOutput {
+		entry.Output = entry.Output[len(entry.Output)-maxHistoryOutput:]
+	}
+	return entry
+}
+
+func AppendHistory(entry HistoryEntry) error {
+	path, err := HistoryFilePath()
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// ReadHistory returns matching entries oldest first; with a Limit only the
+// most recent ones are kept.
+func ReadHistory(filter HistoryFilter) ([]HistoryEntry, error) {
+	path, err := HistoryFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			slog.Warn("skipping malformed history line", "err", err)
+			continue
+		}
+		if filter.match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, err
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+func (e HistoryEntry) String() string {
+	status := "ok"
+	if e.ExitCode != 0 {
+		status = fmt.Sprintf("exit %d", e.ExitCode)
+	}
+	target := e.Tool
+	if e.Version != "" {
+		target += " " + e.Version
+	}
+	if e.Detail != "" {
+		target += " " + e.Detail
+	}
+	return fmt.Sprintf("%s %s %s (%s, %s@%s)", e.Start.Format("2006-01-02 15:04"), e.Action, target, status, e.User, e.Host)
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// InstallRequest asks the running tray to install a version a project pins.
+type InstallRequest struct {
+	Tool    string `json:"tool"`
+	Version string `json:"version"`
+	File    string `json:"file"`
+}
+
+var (
+	requestToolPattern    = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	requestVersionPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+/*-]*$`)
+)
+
+// Validate rejects anything that is not a plain candidate and version, since
+// both end up in a shell command.
+func (r InstallRequest) Validate() error {
+	if !requestToolPattern.MatchString(r.Tool) || !requestVersionPattern.MatchString(r.Version) {
+		return fmt.Errorf("invalid install request for %q %q", r.Tool, r.Version)
+	}
+	return nil
+}
+
+func TraySocketPath() (string, error) {
+	dir, err := AppCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tray.sock"), nil
+}
+
+// ListenInstallRequests accepts install requests from `sdkuigo env` on a
+// socket only the current user can reach. The returned func stops
+// listening.
+func ListenInstallRequests(handle func(InstallRequest)) (func(), error) {
+	path, err := TraySocketPath()
+	if err != nil {
+		return nil, err
+	}
+	// A socket left behind by a crashed tray would make Listen fail.
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					slog.Error("accepting install request", "err", err)
+				}
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				var req InstallRequest
+				if err := json.NewDecoder(conn).Decode(&req); err != nil {
+					slog.Warn("reading install request", "err", err)
+					return
+				}
+				if err := req.Validate(); err != nil {
+					slog.Warn("rejecting install request", "err", err)
+					return
+				}
+				slog.Info("install requested", "tool", req.Tool, "version", req.Version, "file", req.File)
+				handle(req)
+			}()
+		}
+	}()
+	return func() { listener.Close() }, nil
+}
+
+// RequestInstall hands req to the running tray; it fails when no tray is
+// listening.
+func RequestInstall(req InstallRequest) error {
+	path, err := TraySocketPath()
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return json.NewEncoder(conn).Encode(req)
+}
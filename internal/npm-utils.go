@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// bundledPackages ship with every Node version and are never synced.
+var bundledPackages = map[string]bool{"npm": true, "corepack": true}
+
+// GlobalPackage is a package installed with `npm install -g`. Linked
+// packages come from `npm link` and point at a local checkout.
+type GlobalPackage struct {
+	Name    string
+	Version string
+	Linked  bool
+}
+
+func (p GlobalPackage) String() string {
+	if p.Linked {
+		return p.Name + "@" + p.Version + " (linked)"
+	}
+	return p.Name + "@" + p.Version
+}
+
+// Spec is what `npm install -g` is given to install the same version.
+func (p GlobalPackage) Spec() string {
+	return p.Name + "@" + p.Version
+}
+
+// GlobalPackages lists the global packages of an installed Node version by
+// reading lib/node_modules, so it works offline and without loading nvm.
+// The packages bundled with Node are left out.
+func GlobalPackages(version string) ([]GlobalPackage, error) {
+	dir := filepath.Join(LocalNodeHome(version), "lib", "node_modules")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var packages []GlobalPackage
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, "@") {
+			scoped, err := os.ReadDir(filepath.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			for _, s := range scoped {
+				if p, ok := readGlobalPackage(dir, name+"/"+s.Name(), s); ok {
+					packages = append(packages, p)
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(name, ".") || bundledPackages[name] {
+			continue
+		}
+		if p, ok := readGlobalPackage(dir, name, entry); ok {
+			packages = append(packages, p)
+		}
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+	return packages, nil
+}
+
+func readGlobalPackage(dir string, name string, entry os.DirEntry) (GlobalPackage, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name), "package.json"))
+	if err != nil {
+		return GlobalPackage{}, false
+	}
+	var manifest struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		slog.Warn("reading global package", "package", name, "err", err)
+		return GlobalPackage{}, false
+	}
+	return GlobalPackage{Name: name, Version: manifest.Version, Linked: entry.Type()&os.ModeSymlink != 0}, true
+}
+
+// MissingGlobalPackages is what sync would install into to: the packages of
+// from that to does not have at all. Linked packages are skipped since they
+// belong to a checkout, not to a Node version.
+func MissingGlobalPackages(from []GlobalPackage, to []GlobalPackage) []GlobalPackage {
+	have := make(map[string]bool)
+	for _, p := range to {
+		have[p.Name] = true
+	}
+	var missing []GlobalPackage
+	for _, p := range from {
+		if !p.Linked && !have[p.Name] {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// InstallGlobalPackages installs packages into the global packages of the
+// installed Node version, at the versions given.
+func InstallGlobalPackages(version string, packages []GlobalPackage) (string, error) {
+	if len(packages) == 0 {
+		return "", nil
+	}
+	specs := make([]string, len(packages))
+	for i, p := range packages {
+		specs[i] = shellQuote(p.Spec())
+	}
+	slog.Info("installing global packages", "node", version, "packages", specs)
+	return nodeExec(version, "npm install -g "+strings.Join(specs, " "))
+}
+
+// SyncGlobalPackages installs the global packages of from that are missing
+// from to and returns what was installed.
+func SyncGlobalPackages(from string, to string) ([]GlobalPackage, string, error) {
+	source, err := GlobalPackages(from)
+	if err != nil {
+		return nil, "", err
+	}
+	target, err := GlobalPackages(to)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, "", err
+	}
+	missing := MissingGlobalPackages(source, target)
+	out, err := InstallGlobalPackages(to, missing)
+	return missing, out, err
+}
+
+// reinstallPackagesFrom is the installed default Node version whose global
+// packages a new install of version should get, or "" when the
+// reinstall_global_packages setting is off or there is none.
+func reinstallPackagesFrom(version string) string {
+	if !CurrentConfig().ReinstallGlobalPackages {
+		return ""
+	}
+	versions, err := LocalNodeVersions()
+	if err != nil {
+		return ""
+	}
+	for _, v := range versions {
+		if v.Use && v.Identifier != version {
+			return v.Identifier
+		}
+	}
+	return ""
+}
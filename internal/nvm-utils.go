@@ -1,40 +1,80 @@
 package internal
 
 import (
-	"fmt"
-	"os/exec"
+	"log/slog"
+	"os"
 	"regexp"
 	"strings"
 )
 
-var (
-	defaultNvmEnv = `export NVM_DIR="$HOME/.nvm"; [ -s "$NVM_DIR/nvm.sh" ] && \. "$NVM_DIR/nvm.sh"; [ -s "$NVM_DIR/bash_completion" ] && \. "$NVM_DIR/bash_completion"`
+const (
+	NodeLTSAlias     = "lts/*"
+	NodeCurrentAlias = "node"
 )
 
-func InstallNVM() {
-	// Install NVM
-	EnvWrite(defaultNvmEnv, "NVM", "export NVM_DIR")
-	out, err := CommandExec([]string{defaultNvmEnv + "&& nvm --version"})
-	if err != nil {
-		fmt.Println("Error running command:", err)
-		fmt.Println("Installing NVM")
+// nodeLTSPattern picks the codename out of `nvm ls-remote` annotations such
+// as "(LTS: Iron)" or "(Latest LTS: Iron)".
+var nodeLTSPattern = regexp.MustCompile(`LTS: ([^)]+)\)`)
 
-		exec.Command("bash", "-c", "curl -o- https://raw.githubusercontent.com/nvm-sh/nvm/v0.39.7/install.sh | bash").Run()
+// nvmEnv loads nvm from the configured NVM_DIR for commands run by the app.
+func nvmEnv() string {
+	return `export NVM_DIR="` + NVMDir() + `"; [ -s "$NVM_DIR/nvm.sh" ] && \. "$NVM_DIR/nvm.sh"; [ -s "$NVM_DIR/bash_completion" ] && \. "$NVM_DIR/bash_completion"`
+}
 
-		return
+// InstallNVM bootstraps nvm from the configured, verified installer script
+// or release tarball when nvm cannot be loaded.
+func InstallNVM() error {
+	out, err := CommandExec([]string{nvmEnv() + "&& nvm --version"})
+	if err == nil {
+		slog.Info("NVM is already installed", "version", strings.TrimSpace(out))
+		return nil
 	}
-	fmt.Println("NVM is already installed", string(out))
+	slog.Info("installing NVM", "err", err)
+	return runNVMInstaller()
+}
+
+// UpgradeNVM runs the configured installer over the existing nvm; the
+// installer checks out the release it belongs to.
+func UpgradeNVM() error {
+	slog.Info("upgrading NVM", "version", CurrentConfig().Bootstrap.NVM.Version)
+	return runNVMInstaller()
+}
+
+func runNVMInstaller() error {
+	installer := CurrentConfig().Bootstrap.NVM
+	if installer.SHA256 == "" && installer.Source == DefaultNVMInstaller {
+		installer.SHA256 = NVMInstallerSHA256[installer.Version]
+	}
+	return bootstrap("NVM", NVMDir(), installer, func(path string) error {
+		if isGzip(path) {
+			if err := os.MkdirAll(NVMDir(), 0755); err != nil {
+				return err
+			}
+			return extractTarball(path, NVMDir())
+		}
+		_, err := CommandExecCombined([]string{"PROFILE=/dev/null bash " + shellQuote(path)})
+		return err
+	}, func() string {
+		version, err := NVMVersion()
+		if err != nil {
+			return installer.Version
+		}
+		return version
+	})
 }
 
-func NodeVersionList() []Candidate {
+func NodeVersionList() ([]Candidate, error) {
 	// List Node versions
 	var candidates []Candidate
-	out, err := CommandExec([]string{defaultNvmEnv + "&& nvm ls-remote"})
+	out, err := CommandExec([]string{nvmEnv() + "&& nvm ls-remote"})
 	if err != nil {
-		return candidates
+		return candidates, err
 	}
 	lines := strings.Split(out, "\n")
-	installedMap := NodeLocalInstallList()
+	installedMap, err := NodeLocalInstallList()
+	if err != nil {
+		return candidates, err
+	}
 	for _, line := range lines {
 		regexPattern := `\b(v[0-9]+\.[0-9]+\.[0-9]+)\b`
 		re := regexp.MustCompile(regexPattern)
@@ -51,41 +91,110 @@ func NodeVersionList() []Candidate {
 				candidate.Install = false
 			}
 		}
+		if lts := nodeLTSPattern.FindStringSubmatch(line); lts != nil {
+			candidate.LTS = lts[1]
+		}
 
 		candidates = append(candidates, candidate)
 	}
-	return candidates
+	return candidates, nil
 }
 
-func OpenNodeFolder(version string) {
-	out, _ := CommandExec([]string{defaultNvmEnv + "&& nvm which " + version})
+func OpenNodeFolder(version string) error {
+	if home := LocalNodeHome(version); FileExists(home) {
+		return OpenPath(home)
+	}
+	out, err := CommandExec([]string{nvmEnv() + "&& nvm which " + version})
+	if err != nil {
+		return err
+	}
 	out = strings.ReplaceAll(out, "/bin/node", "")
-	openFolder(strings.TrimSpace(out))
+	return OpenPath(strings.TrimSpace(out))
 }
 
-func InstallNode(version string) {
-	fmt.Println("Installing Node version", version)
-	_, _ = CommandExec([]string{defaultNvmEnv + "&& nvm install " + version + " && nvm alias default " + version})
-	fmt.Println("Installed Node version", version)
+// InstallNode installs version and makes it the default. With the
+// reinstall_global_packages setting on, the global packages of the previous
+// default are installed into it as well.
+func InstallNode(version string) (string, error) {
+	return installNodeDefault(version, version)
 }
 
-func UninstallNode(version string) {
-	fmt.Println("Uninstalling Node version", version)
-	_, _ = CommandExec([]string{defaultNvmEnv + "&& nvm uninstall " + version})
-	fmt.Println("Uninstalled Node version", version)
+// InstallNodeAlias installs the version an nvm alias such as lts/* or node
+// stands for and makes the alias itself the default, so the default follows
+// it to newer releases installed later. version is what alias resolves to.
+func InstallNodeAlias(alias string, version string) (string, error) {
+	return installNodeDefault(alias, version)
 }
 
-func NVMVersion() string {
-	out, err := CommandExec([]string{defaultNvmEnv + "&& nvm --version"})
+func installNodeDefault(spec string, version string) (string, error) {
+	install := "nvm install " + shellQuote(spec)
+	from := reinstallPackagesFrom(version)
+	if from != "" {
+		install += " --reinstall-packages-from=" + from
+	}
+	slog.Info("installing node", "version", version, "spec", spec, "packages_from", from)
+	out, err := CommandExec([]string{nvmEnv() + "&& " + install + " && nvm alias default " + shellQuote(spec)})
 	if err != nil {
-		return ""
+		return out, err
 	}
-	return strings.TrimSpace(out)
+	slog.Info("installed node", "version", version)
+	return out, nil
 }
 
-func NodeLocalInstallList() map[string]Candidate {
+// LatestNodeVersions picks the newest LTS release and the newest release
+// overall from a Node version list: what nvm's lts/* and node aliases
+// resolve to. Either is empty when the list has none.
+func LatestNodeVersions(versions []Candidate) (Candidate, Candidate) {
+	var lts, current Candidate
+	for _, v := range versions {
+		if current.Identifier == "" || newerVersion(v.Identifier, current.Identifier) {
+			current = v
+		}
+		if v.LTS != "" && (lts.Identifier == "" || newerVersion(v.Identifier, lts.Identifier)) {
+			lts = v
+		}
+	}
+	return lts, current
+}
+
+// InstallNodeVersion installs version for a project's .nvmrc and leaves the
+// default alias alone.
+func InstallNodeVersion(version string) (string, error) {
+	slog.Info("installing node", "version", version)
+	out, err := CommandExec([]string{nvmEnv() + "&& nvm install --no-progress " + shellQuote(version)})
+	if err != nil {
+		return out, err
+	}
+	slog.Info("installed node", "version", version)
+	return out, nil
+}
+
+func UninstallNode(version string) (string, error) {
+	slog.Info("uninstalling node", "version", version)
+	out, err := CommandExec([]string{nvmEnv() + "&& nvm uninstall " + version})
+	if err != nil {
+		return out, err
+	}
+	slog.Info("uninstalled node", "version", version)
+	return out, nil
+}
+
+func NVMVersion() (string, error) {
+	out, err := CommandExec([]string{nvmEnv() + "&& nvm --version"})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// NodeLocalInstallList maps installed versions to their candidate entry. An
+// nvm without any Node installed is not an error.
+func NodeLocalInstallList() (map[string]Candidate, error) {
 	var installCandidates = make(map[string]Candidate)
-	out, _ := CommandExec([]string{defaultNvmEnv + "&& nvm ls node"})
+	out, err := CommandExec([]string{nvmEnv() + "&& nvm ls node"})
+	if err != nil && !strings.Contains(out, "N/A") {
+		return installCandidates, err
+	}
 	lines := strings.Split(out, "\n")
 	for _, line := range lines {
 		var candidate Candidate
@@ -107,5 +216,5 @@ func NodeLocalInstallList() map[string]Candidate {
 			installCandidates[candidate.Identifier] = candidate
 		}
 	}
-	return installCandidates
+	return installCandidates, nil
 }
@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const onlineCheckAddress = "api.sdkman.io:443"
+
+// SDKManDir is sdkman_dir from the settings, else SDKMAN_DIR as the app was
+// started with, else ~/.sdkman.
+func SDKManDir() string {
+	return CurrentConfig().sdkmanDir
+}
+
+// NVMDir is nvm_dir from the settings, else NVM_DIR as the app was started
+// with, else ~/.nvm.
+func NVMDir() string {
+	return CurrentConfig().nvmDir
+}
+
+func SDKManInitScript() string {
+	return filepath.Join(SDKManDir(), "bin", "sdkman-init.sh")
+}
+
+func SDKManCandidatesDir() string {
+	return filepath.Join(SDKManDir(), "candidates")
+}
+
+func NodeVersionsDir() string {
+	return filepath.Join(NVMDir(), "versions", "node")
+}
+
+// IsOnline reports whether the SDKMan API can be reached at all; it is only a
+// TCP dial so it stays cheap enough to run at startup.
+func IsOnline() bool {
+	conn, err := net.DialTimeout("tcp", onlineCheckAddress, 3*time.Second)
+	if err != nil {
+		slog.Info("network check failed, assuming offline", "err", err)
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// LocalCandidateNames lists the SDKMan candidates that have a directory under
+// ~/.sdkman/candidates.
+func LocalCandidateNames() ([]string, error) {
+	entries, err := os.ReadDir(SDKManCandidatesDir())
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// LocalCandidateVersions lists the installed versions of candidate; the one
+// the current symlink points at is marked as used.
+func LocalCandidateVersions(candidate string) ([]Candidate, error) {
+	dir := filepath.Join(SDKManCandidatesDir(), candidate)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	current := ""
+	if target, err := os.Readlink(filepath.Join(dir, "current")); err == nil {
+		current = filepath.Base(target)
+	}
+	var versions []Candidate
+	for _, entry := range entries {
+		if entry.Name() == "current" {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(dir, entry.Name()))
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		versions = append(versions, Candidate{
+			Identifier: entry.Name(),
+			Install:    true,
+			Use:        entry.Name() == current,
+			Custom:     entry.Type()&os.ModeSymlink != 0,
+		})
+	}
+	return versions, nil
+}
+
+// UseLocalCandidate points the candidate's current symlink at version, which
+// is all `sdk default` does for an installed version.
+func UseLocalCandidate(candidate string, version string) (string, error) {
+	dir := filepath.Join(SDKManCandidatesDir(), candidate)
+	if !FileExists(filepath.Join(dir, version)) {
+		return "", &CommandError{Kind: ErrNotInstalled, Command: "sdk default " + candidate + " " + version}
+	}
+	current := filepath.Join(dir, "current")
+	if err := os.Remove(current); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if err := os.Symlink(version, current); err != nil {
+		return "", err
+	}
+	slog.Info("switched default offline", "candidate", candidate, "version", version)
+	return "Default " + candidate + " version set to " + version, nil
+}
+
+func LocalCandidateHome(candidate string, version string) string {
+	return filepath.Join(SDKManCandidatesDir(), candidate, version)
+}
+
+// LocalNodeVersions lists the Node versions under $NVM_DIR/versions/node; the
+// one nvm's default alias resolves to is marked as used.
+func LocalNodeVersions() ([]Candidate, error) {
+	entries, err := os.ReadDir(NodeVersionsDir())
+	if err != nil {
+		return nil, err
+	}
+	var installed []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), "v") {
+			installed = append(installed, entry.Name())
+		}
+	}
+	defaultVersion := NodeDefaultVersion(installed)
+	var versions []Candidate
+	for _, version := range installed {
+		versions = append(versions, Candidate{
+			Identifier: version,
+			Install:    true,
+			Use:        version == defaultVersion,
+		})
+	}
+	return versions, nil
+}
+
+// NodeDefaultVersion resolves nvm's default alias against installed.
+func NodeDefaultVersion(installed []string) string {
+	return ResolveNodeVersion("default", installed)
+}
+
+// ResolveNodeVersion resolves spec against installed the way nvm does:
+// aliases may point at other aliases (lts/*, lts/iron), at "node" or
+// "stable", or at a partial version such as "20".
+func ResolveNodeVersion(spec string, installed []string) string {
+	for depth := 0; depth < 10; depth++ {
+		data, err := os.ReadFile(filepath.Join(NVMDir(), "alias", spec))
+		if err != nil {
+			break
+		}
+		spec = strings.TrimSpace(string(data))
+	}
+	return matchNodeVersion(spec, installed)
+}
+
+func matchNodeVersion(spec string, installed []string) string {
+	sorted := make([]Candidate, 0, len(installed))
+	for _, version := range installed {
+		sorted = append(sorted, Candidate{Identifier: version})
+	}
+	sorted = SortCandidates(sorted)
+	if spec == "node" || spec == "stable" {
+		if len(sorted) > 0 {
+			return sorted[0].Identifier
+		}
+		return ""
+	}
+	spec = "v" + strings.TrimPrefix(spec, "v")
+	for _, c := range sorted {
+		if c.Identifier == spec || strings.HasPrefix(c.Identifier, spec+".") {
+			return c.Identifier
+		}
+	}
+	return ""
+}
+
+// NodeDefaultBin is the bin directory of the Node version nvm's default alias
+// resolves to, or "" when there is none.
+func NodeDefaultBin() string {
+	versions, err := LocalNodeVersions()
+	if err != nil {
+		return ""
+	}
+	for _, v := range versions {
+		if v.Use {
+			return filepath.Join(NodeVersionsDir(), v.Identifier, "bin")
+		}
+	}
+	return ""
+}
+
+func UseLocalNode(version string) (string, error) {
+	if !FileExists(filepath.Join(NodeVersionsDir(), version)) {
+		return "", &CommandError{Kind: ErrNotInstalled, Command: "nvm alias default " + version}
+	}
+	return CommandExec([]string{nvmEnv() + "&& nvm alias default " + version})
+}
+
+func LocalNodeHome(version string) string {
+	return filepath.Join(NodeVersionsDir(), version)
+}
@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	ProviderSDKMan = "sdkman"
+	ProviderNVM    = "nvm"
+)
+
+type Operation struct {
+	Provider string
+	Action   string
+	Tool     string
+	Version  string
+	// Detail tells apart operations on the same version, e.g. the package
+	// manager a pin is for.
+	Detail string
+	Run    func() (string, error)
+}
+
+func (o Operation) Key() string {
+	key := o.Provider + " " + o.Action + " " + o.Tool + " " + o.Version
+	if o.Detail != "" {
+		key += " " + o.Detail
+	}
+	return key
+}
+
+func (o Operation) String() string {
+	s := o.Action + " " + o.Tool
+	if o.Version != "" {
+		s += " " + o.Version
+	}
+	if o.Detail != "" {
+		s += " " + o.Detail
+	}
+	return s
+}
+
+type QueueState struct {
+	Running []Operation
+	Pending []Operation
+}
+
+// OperationQueue runs operations one at a time per provider, so SDKMan and NVM
+// never see two mutations at once while still progressing independently.
+type OperationQueue struct {
+	mu       sync.Mutex
+	pending  map[string][]Operation
+	running  map[string]*Operation
+	OnChange func(state QueueState)
+	// OnDone, if set, gets the result of every operation once the next one
+	// of its provider has started, so it may block, e.g. on an error
+	// dialog.
+	OnDone func(op Operation, err error)
+}
+
+func NewOperationQueue() *OperationQueue {
+	return &OperationQueue{
+		pending: make(map[string][]Operation),
+		running: make(map[string]*Operation),
+	}
+}
+
+// Enqueue adds op to its provider's queue and returns false when an identical
+// operation is already pending or running.
+func (q *OperationQueue) Enqueue(op Operation) bool {
+	q.mu.Lock()
+	if q.containsLocked(op.Key()) {
+		q.mu.Unlock()
+		slog.Info("skipping duplicate operation", "op", op.Key())
+		return false
+	}
+	q.pending[op.Provider] = append(q.pending[op.Provider], op)
+	if q.running[op.Provider] == nil {
+		q.startNextLocked(op.Provider)
+	}
+	q.mu.Unlock()
+	q.notify()
+	return true
+}
+
+func (q *OperationQueue) State() QueueState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var state QueueState
+	for _, op := range q.running {
+		state.Running = append(state.Running, *op)
+	}
+	for _, ops := range q.pending {
+		state.Pending = append(state.Pending, ops...)
+	}
+	return state
+}
+
+func (q *OperationQueue) containsLocked(key string) bool {
+	for _, op := range q.running {
+		if op.Key() == key {
+			return true
+		}
+	}
+	for _, ops := range q.pending {
+		for _, op := range ops {
+			if op.Key() == key {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (q *OperationQueue) startNextLocked(provider string) {
+	ops := q.pending[provider]
+	if len(ops) == 0 {
+		delete(q.pending, provider)
+		delete(q.running, provider)
+		return
+	}
+	op := ops[0]
+	q.pending[provider] = ops[1:]
+	q.running[provider] = &op
+	go q.run(op)
+}
+
+func (q *OperationQueue) run(op Operation) {
+	slog.Info("running operation", "op", op.Key())
+	start := time.Now()
+	var output string
+	var err error
+	if op.Run != nil {
+		output, err = op.Run()
+	}
+	if err := AppendHistory(NewHistoryEntry(op, start, time.Now(), output, err)); err != nil {
+		slog.Error("writing history", "err", err)
+	}
+	q.mu.Lock()
+	q.startNextLocked(op.Provider)
+	q.mu.Unlock()
+	q.notify()
+	if q.OnDone != nil {
+		q.OnDone(op, err)
+	}
+}
+
+func (q *OperationQueue) notify() {
+	if q.OnChange != nil {
+		q.OnChange(q.State())
+	}
+}
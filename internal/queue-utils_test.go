@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// isolateUserDirs points the config and cache directories at a temp dir, so
+// history and caches written by the code under test stay out of the user's.
+func isolateUserDirs(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", dir)
+}
+
+// blockingOp returns an operation that reports its start on started and
+// runs until release is closed.
+func blockingOp(provider string, version string, started chan<- string, release <-chan struct{}) Operation {
+	return Operation{Provider: provider, Action: "install", Tool: "java", Version: version, Run: func() (string, error) {
+		started <- provider + " " + version
+		<-release
+		return "", nil
+	}}
+}
+
+func waitFor(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an operation")
+		return ""
+	}
+}
+
+func waitIdle(t *testing.T, q *OperationQueue) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if state := q.State(); len(state.Running) == 0 && len(state.Pending) == 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("queue did not drain")
+}
+
+func TestOperationQueueRunsFIFOPerProvider(t *testing.T) {
+	isolateUserDirs(t)
+	q := NewOperationQueue()
+	started := make(chan string, 3)
+	releases := []chan struct{}{make(chan struct{}), make(chan struct{}), make(chan struct{})}
+	for i, version := range []string{"1", "2", "3"} {
+		if !q.Enqueue(blockingOp(ProviderSDKMan, version, started, releases[i])) {
+			t.Fatalf("enqueue %s rejected", version)
+		}
+	}
+	var order []string
+	for i, release := range releases {
+		order = append(order, waitFor(t, started))
+		// The operation blocks until released, so the state is settled.
+		if state := q.State(); len(state.Running) != 1 || len(state.Pending) != len(releases)-1-i {
+			t.Fatalf("while %s runs got %+v, want it alone running and the rest pending", order[i], state)
+		}
+		close(release)
+	}
+	if want := []string{"sdkman 1", "sdkman 2", "sdkman 3"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("ran %v, want %v", order, want)
+	}
+	waitIdle(t, q)
+}
+
+func TestOperationQueueRunsProvidersInParallel(t *testing.T) {
+	isolateUserDirs(t)
+	q := NewOperationQueue()
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	q.Enqueue(blockingOp(ProviderSDKMan, "1", started, release))
+	q.Enqueue(blockingOp(ProviderNVM, "1", started, release))
+	got := map[string]bool{waitFor(t, started): true, waitFor(t, started): true}
+	if !got["sdkman 1"] || !got["nvm 1"] {
+		t.Errorf("started %v, want both providers at once", got)
+	}
+	if state := q.State(); len(state.Running) != 2 {
+		t.Errorf("running %v, want two operations", state.Running)
+	}
+	close(release)
+	waitIdle(t, q)
+}
+
+func TestOperationQueueRejectsDuplicates(t *testing.T) {
+	isolateUserDirs(t)
+	q := NewOperationQueue()
+	started := make(chan string, 3)
+	release := make(chan struct{})
+	running := blockingOp(ProviderSDKMan, "1", started, release)
+	pending := blockingOp(ProviderSDKMan, "2", started, release)
+	if !q.Enqueue(running) || !q.Enqueue(pending) {
+		t.Fatal("first enqueue rejected")
+	}
+	waitFor(t, started)
+	if q.Enqueue(running) {
+		t.Error("accepted a duplicate of the running operation")
+	}
+	if q.Enqueue(pending) {
+		t.Error("accepted a duplicate of a pending operation")
+	}
+	other := pending
+	other.Action = "uninstall"
+	if !q.Enqueue(other) {
+		t.Error("rejected an operation with a different key")
+	}
+	close(release)
+	waitIdle(t, q)
+	if !q.Enqueue(running) {
+		t.Error("rejected an operation that already finished")
+	}
+	waitFor(t, started)
+	waitIdle(t, q)
+}
+
+func TestOperationQueueStateAndOnChange(t *testing.T) {
+	isolateUserDirs(t)
+	q := NewOperationQueue()
+	var mu sync.Mutex
+	var changes []QueueState
+	idle := make(chan string, 1)
+	q.OnChange = func(state QueueState) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, state)
+		if len(state.Running) == 0 && len(state.Pending) == 0 {
+			select {
+			case idle <- "idle":
+			default:
+			}
+		}
+	}
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	first := blockingOp(ProviderSDKMan, "1", started, release)
+	second := blockingOp(ProviderSDKMan, "2", started, release)
+	q.Enqueue(first)
+	q.Enqueue(second)
+	waitFor(t, started)
+
+	state := q.State()
+	if len(state.Running) != 1 || state.Running[0].Key() != first.Key() {
+		t.Errorf("running %v, want %s", state.Running, first.Key())
+	}
+	if len(state.Pending) != 1 || state.Pending[0].Key() != second.Key() {
+		t.Errorf("pending %v, want %s", state.Pending, second.Key())
+	}
+	close(release)
+	waitFor(t, started)
+	waitFor(t, idle)
+
+	// Callbacks come from several goroutines, so only which states were
+	// reported is checked, not how often or in what order.
+	mu.Lock()
+	defer mu.Unlock()
+	reported := make(map[[2]int]bool)
+	for _, change := range changes {
+		reported[[2]int{len(change.Running), len(change.Pending)}] = true
+	}
+	for _, want := range [][2]int{{1, 1}, {0, 0}} {
+		if !reported[want] {
+			t.Errorf("no change with %d running and %d pending, got %v", want[0], want[1], changes)
+		}
+	}
+}
+
+func TestOperationQueueKeysIncludeDetail(t *testing.T) {
+	isolateUserDirs(t)
+	q := NewOperationQueue()
+	started := make(chan string, 2)
+	release := make(chan struct{})
+	pnpm := blockingOp(ProviderNVM, "v20.11.1", started, release)
+	pnpm.Action, pnpm.Detail = "pin", "pnpm"
+	yarn := pnpm
+	yarn.Detail = "yarn"
+	if !q.Enqueue(pnpm) || !q.Enqueue(yarn) {
+		t.Error("pins of different package managers collided")
+	}
+	if q.Enqueue(yarn) {
+		t.Error("accepted a second yarn pin")
+	}
+	close(release)
+	waitFor(t, started)
+	waitFor(t, started)
+	waitIdle(t, q)
+}
+
+func TestOperationQueueOnDoneDoesNotHoldUpTheQueue(t *testing.T) {
+	isolateUserDirs(t)
+	q := NewOperationQueue()
+	failure := errors.New("install failed")
+	dialog := make(chan struct{})
+	done := make(chan error, 2)
+	q.OnDone = func(op Operation, err error) {
+		done <- err
+		if err != nil {
+			// An error dialog stays open until the user closes it.
+			<-dialog
+		}
+	}
+	started := make(chan string, 1)
+	q.Enqueue(Operation{Provider: ProviderSDKMan, Action: "install", Tool: "java", Version: "1", Run: func() (string, error) {
+		return "", failure
+	}})
+	q.Enqueue(Operation{Provider: ProviderSDKMan, Action: "install", Tool: "java", Version: "2", Run: func() (string, error) {
+		started <- "sdkman 2"
+		return "", nil
+	}})
+	if got := waitFor(t, started); got != "sdkman 2" {
+		t.Fatalf("started %s", got)
+	}
+	// The second operation finishing while the first one's dialog is still
+	// open shows it is not held up. The two may be reported in any order.
+	var failed, succeeded int
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-done:
+			if errors.Is(err, failure) {
+				failed++
+			} else if err == nil {
+				succeeded++
+			} else {
+				t.Errorf("OnDone got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for OnDone")
+		}
+	}
+	if failed != 1 || succeeded != 1 {
+		t.Errorf("OnDone reported %d failures and %d successes, want one each", failed, succeeded)
+	}
+	close(dialog)
+	waitIdle(t, q)
+}
@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sdkmanLatestURL = "https://api.sdkman.io/2/broker/version/sdkman/script/stable"
+	nvmLatestURL    = "https://api.github.com/repos/nvm-sh/nvm/releases/latest"
+
+	releaseTimeout = 10 * time.Second
+
+	DefaultUpdateCheckHours = 24
+)
+
+// ToolRelease compares the installed SDKMan or NVM with the latest release.
+type ToolRelease struct {
+	Tool    string
+	Current string
+	Latest  string
+}
+
+func (r ToolRelease) Outdated() bool {
+	return r.Current != "" && r.Latest != "" && newerVersion(r.Latest, r.Current)
+}
+
+// CheckResult is everything one background check found.
+type CheckResult struct {
+	Updates  []VersionUpdate
+	Releases []ToolRelease
+	NewLTS   []string
+}
+
+// Summary is the text of the single notification for r, or "" when there
+// is nothing new.
+func (r CheckResult) Summary() string {
+	var parts []string
+	if len(r.Updates) > 0 {
+		var names []string
+		for _, u := range r.Updates {
+			names = append(names, u.Tool+" "+u.Latest)
+		}
+		parts = append(parts, fmt.Sprintf("%d updates: %s", len(r.Updates), strings.Join(names, ", ")))
+	}
+	for _, release := range r.Releases {
+		if release.Outdated() {
+			parts = append(parts, release.Tool+" "+release.Latest+" is available")
+		}
+	}
+	if len(r.NewLTS) > 0 {
+		parts = append(parts, "New LTS: "+strings.Join(r.NewLTS, ", "))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// SDKManRelease reads the installed script version from $SDKMAN_DIR/var and
+// asks the SDKMan API for the latest stable one.
+func SDKManRelease() (ToolRelease, error) {
+	release := ToolRelease{Tool: "SDKMan"}
+	current, err := SDKManScriptVersion()
+	if err != nil {
+		return release, err
+	}
+	release.Current = current
+	latest, err := httpGet(sdkmanLatestURL)
+	if err != nil {
+		return release, err
+	}
+	release.Latest = strings.TrimSpace(string(latest))
+	return release, nil
+}
+
+// NVMRelease compares `nvm --version` with the latest GitHub release.
+func NVMRelease() (ToolRelease, error) {
+	release := ToolRelease{Tool: "NVM"}
+	current, err := NVMVersion()
+	if err != nil {
+		return release, err
+	}
+	release.Current = current
+	data, err := httpGet(nvmLatestURL)
+	if err != nil {
+		return release, err
+	}
+	var latest struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.Unmarshal(data, &latest); err != nil {
+		return release, err
+	}
+	release.Latest = strings.TrimPrefix(latest.TagName, "v")
+	return release, nil
+}
+
+func httpGet(url string) ([]byte, error) {
+	client := http.Client{Timeout: releaseTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// NewLTSLines reports LTS lines in the cached Java and Node lists that are
+// newer than any seen before. The first call only records what exists.
+func NewLTSLines() ([]string, error) {
+	latest := make(map[string]int)
+	names := make(map[string]string)
+	if entry, ok := LoadCache(SDKManCacheKey("java")); ok {
+		for _, c := range entry.Candidates {
+			// Early access builds of an upcoming LTS are not a release yet.
+			if strings.Contains(c.Identifier, ".ea.") {
+				continue
+			}
+			if major := JavaVersion(c.Identifier); IsJavaLTS(c.Identifier) && major > latest["java"] {
+				latest["java"] = major
+				names["java"] = "java " + strconv.Itoa(major)
+			}
+		}
+	}
+	if entry, ok := LoadCache(NodeCacheKey); ok {
+		for _, c := range entry.Candidates {
+			if major := MajorVersion(c.Identifier); c.LTS != "" && major > latest[NodeWatchKey] {
+				latest[NodeWatchKey] = major
+				names[NodeWatchKey] = fmt.Sprintf("node %d (%s)", major, c.LTS)
+			}
+		}
+	}
+
+	path, err := ltsSeenPath()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]int)
+	data, err := os.ReadFile(path)
+	first := errors.Is(err, os.ErrNotExist)
+	if err != nil && !first {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &seen); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+	var fresh []string
+	for tool, major := range latest {
+		if major > seen[tool] {
+			if !first && seen[tool] > 0 {
+				fresh = append(fresh, names[tool])
+			}
+			seen[tool] = major
+		}
+	}
+	sort.Strings(fresh)
+	data, err = json.Marshal(seen)
+	if err != nil {
+		return nil, err
+	}
+	return fresh, os.WriteFile(path, data, 0644)
+}
+
+func ltsSeenPath() (string, error) {
+	dir, err := AppCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lts-seen.json"), nil
+}
+
+// Unnotified drops the updates and releases of r that an earlier check
+// already notified about, and remembers the rest, so a restart or one new
+// update does not bring back the old ones. New LTS lines are already only
+// reported once by NewLTSLines.
+func (r CheckResult) Unnotified() (CheckResult, error) {
+	path, err := updatesNotifiedPath()
+	if err != nil {
+		return r, err
+	}
+	notified := make(map[string]bool)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return r, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &notified); err != nil {
+			return r, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+	fresh := CheckResult{NewLTS: r.NewLTS}
+	for _, u := range r.Updates {
+		if key := u.Tool + "@" + u.Latest; !notified[key] {
+			notified[key] = true
+			fresh.Updates = append(fresh.Updates, u)
+		}
+	}
+	for _, release := range r.Releases {
+		if key := release.Tool + "@" + release.Latest; release.Outdated() && !notified[key] {
+			notified[key] = true
+			fresh.Releases = append(fresh.Releases, release)
+		}
+	}
+	if len(fresh.Updates) == 0 && len(fresh.Releases) == 0 {
+		return fresh, nil
+	}
+	data, err = json.Marshal(notified)
+	if err != nil {
+		return fresh, err
+	}
+	return fresh, os.WriteFile(path, data, 0644)
+}
+
+func updatesNotifiedPath() (string, error) {
+	dir, err := AppCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "updates-notified.json"), nil
+}
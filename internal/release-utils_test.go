@@ -0,0 +1,38 @@
+package internal
+
+import "testing"
+
+func TestUnnotifiedOnlyReportsNewFindings(t *testing.T) {
+	isolateUserDirs(t)
+	java := VersionUpdate{Tool: "java", Current: "21.0.2-tem", Latest: "21.0.3-tem"}
+	maven := VersionUpdate{Tool: "maven", Current: "3.9.6", Latest: "3.9.9"}
+	nvm := ToolRelease{Tool: "NVM", Current: "0.39.7", Latest: "0.40.3"}
+
+	fresh, err := CheckResult{Updates: []VersionUpdate{java}, Releases: []ToolRelease{nvm}}.Unnotified()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fresh.Updates) != 1 || len(fresh.Releases) != 1 {
+		t.Fatalf("first check should report everything, got %+v", fresh)
+	}
+
+	// A restart finds the same update plus a new one.
+	fresh, err = CheckResult{Updates: []VersionUpdate{java, maven}, Releases: []ToolRelease{nvm}}.Unnotified()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fresh.Updates) != 1 || fresh.Updates[0] != maven || len(fresh.Releases) != 0 {
+		t.Errorf("only the maven update is new, got %+v", fresh)
+	}
+	if summary := fresh.Summary(); summary != "1 updates: maven 3.9.9" {
+		t.Errorf("summary = %q", summary)
+	}
+
+	fresh, err = CheckResult{Updates: []VersionUpdate{java, maven}, NewLTS: []string{"java 25"}}.Unnotified()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary := fresh.Summary(); summary != "New LTS: java 25" {
+		t.Errorf("summary = %q, want only the new LTS line", summary)
+	}
+}
@@ -0,0 +1,261 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ResetShell     = "shell"
+	ResetTool      = "tool"
+	ResetAutostart = "autostart"
+)
+
+// CreatedTool is a tool directory the app bootstrapped itself; only those
+// are offered for removal, never installs the user made.
+type CreatedTool struct {
+	Tool      string    `json:"tool"`
+	Path      string    `json:"path"`
+	Version   string    `json:"version,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	SHA256    string    `json:"sha256,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ResetItem is one thing the app added that the reset flow can take back.
+type ResetItem struct {
+	Kind        string
+	Description string
+	Path        string
+	remove      func() error
+}
+
+var createdToolsMu sync.Mutex
+
+func createdToolsPath() (string, error) {
+	dir, err := AppConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "created.json"), nil
+}
+
+// CreatedTools lists the tool directories recorded by RecordCreatedTool.
+func CreatedTools() ([]CreatedTool, error) {
+	path, err := createdToolsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tools []CreatedTool
+	if err := json.Unmarshal(data, &tools); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return tools, nil
+}
+
+// RecordCreatedTool remembers that the app installed a tool, which version
+// and which verified installer it came from.
+func RecordCreatedTool(tool CreatedTool) error {
+	tool.CreatedAt = time.Now()
+	return updateCreatedTools(func(tools []CreatedTool) []CreatedTool {
+		return append(forgetTool(tools, tool.Path), tool)
+	})
+}
+
+func updateCreatedTools(change func([]CreatedTool) []CreatedTool) error {
+	createdToolsMu.Lock()
+	defer createdToolsMu.Unlock()
+	tools, err := CreatedTools()
+	if err != nil {
+		return err
+	}
+	path, err := createdToolsPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(change(tools), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func forgetTool(tools []CreatedTool, path string) []CreatedTool {
+	var kept []CreatedTool
+	for _, tool := range tools {
+		if tool.Path != path {
+			kept = append(kept, tool)
+		}
+	}
+	return kept
+}
+
+// PlanReset lists what the app added and is still there: the managed rc
+// blocks and the unmarked lines earlier versions wrote, the tool
+// directories it bootstrapped and autostart entries. Each rc file is one
+// item, so its block and legacy lines are removed in a single rewrite.
+func PlanReset() ([]ResetItem, error) {
+	var items []ResetItem
+	changes, err := PlanShellRemoval()
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := PlanLegacyRemoval()
+	if err != nil {
+		return nil, err
+	}
+	hasLegacy := make(map[string]bool)
+	for _, l := range legacy {
+		hasLegacy[l.Shell.RCFile] = true
+		found := false
+		for i := range changes {
+			if changes[i].Shell.RCFile == l.Shell.RCFile {
+				changes[i].After = removeLegacyLines(changes[i].After)
+				found = true
+			}
+		}
+		if !found {
+			changes = append(changes, l)
+		}
+	}
+	for _, change := range changes {
+		if !change.Changed() {
+			continue
+		}
+		description := "Remove the SDK UI block from " + change.Shell.RCFile
+		switch {
+		case hasLegacy[change.Shell.RCFile] && strings.Contains(change.Before, shellBlockStart):
+			description = "Remove the SDK UI block and the unmarked SDKMan/NVM lines of earlier versions from " + change.Shell.RCFile
+		case hasLegacy[change.Shell.RCFile]:
+			description = "Remove the unmarked SDKMan/NVM lines of earlier versions from " + change.Shell.RCFile
+		}
+		change := change
+		items = append(items, ResetItem{
+			Kind:        ResetShell,
+			Description: description,
+			Path:        change.Shell.RCFile,
+			remove:      func() error { return ApplyShellChange(change) },
+		})
+	}
+
+	tools, err := CreatedTools()
+	if err != nil {
+		return nil, err
+	}
+	for _, tool := range tools {
+		if !FileExists(tool.Path) {
+			continue
+		}
+		tool := tool
+		items = append(items, ResetItem{
+			Kind:        ResetTool,
+			Description: fmt.Sprintf("Remove %s from %s (installed by SDK UI on %s)", tool.Tool, tool.Path, tool.CreatedAt.Format("2006-01-02")),
+			Path:        tool.Path,
+			remove:      func() error { return removeCreatedTool(tool) },
+		})
+	}
+
+	for _, path := range autostartEntries() {
+		path := path
+		items = append(items, ResetItem{
+			Kind:        ResetAutostart,
+			Description: "Remove the autostart entry " + path,
+			Path:        path,
+			remove:      func() error { return moveToBackupDir(path) },
+		})
+	}
+	return items, nil
+}
+
+// Remove takes the item back, keeping a backup of whatever it deletes.
+func (i ResetItem) Remove() error {
+	if err := i.remove(); err != nil {
+		return fmt.Errorf("%s: %w", i.Description, err)
+	}
+	slog.Info("reset item removed", "kind", i.Kind, "path", i.Path)
+	return nil
+}
+
+// removeCreatedTool renames the tool directory next to itself instead of
+// deleting it; the rename is instant even for gigabytes of SDKs, and the
+// backup can be deleted by hand once nothing is missed.
+func removeCreatedTool(tool CreatedTool) error {
+	backup := backupPath(tool.Path)
+	if err := os.Rename(tool.Path, backup); err != nil {
+		return err
+	}
+	slog.Info("moved tool directory aside", "tool", tool.Tool, "path", tool.Path, "backup", backup)
+	return updateCreatedTools(func(tools []CreatedTool) []CreatedTool {
+		return forgetTool(tools, tool.Path)
+	})
+}
+
+// autostartEntries finds login items for the app in the places each
+// platform starts them from.
+func autostartEntries() []string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	switch runtime.GOOS {
+	case "darwin":
+		dirs = []string{filepath.Join(homeDir, "Library", "LaunchAgents")}
+	case "windows":
+		dirs = []string{filepath.Join(os.Getenv("APPDATA"), "Microsoft", "Windows", "Start Menu", "Programs", "Startup")}
+	default:
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil
+		}
+		dirs = []string{filepath.Join(configDir, "autostart")}
+	}
+	var entries []string
+	for _, dir := range dirs {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			name := strings.ToLower(file.Name())
+			if strings.Contains(name, "sdk-ui-go") || strings.Contains(name, "sdkuigo") {
+				entries = append(entries, filepath.Join(dir, file.Name()))
+			}
+		}
+	}
+	return entries
+}
+
+// moveToBackupDir moves path into the app's backups directory, since a
+// renamed file left in an autostart folder may still be picked up.
+func moveToBackupDir(path string) error {
+	configDir, err := AppConfigDir()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(configDir, "backups", time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	backup := filepath.Join(dir, filepath.Base(path))
+	if err := os.Rename(path, backup); err != nil {
+		return err
+	}
+	slog.Info("moved file to backups", "path", path, "backup", backup)
+	return nil
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlanResetRemovesLegacyLines(t *testing.T) {
+	isolateUserDirs(t)
+	t.Setenv("SHELL", "/bin/bash")
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sdkmanLine, nvmLine := legacyShellLines[0], legacyShellLines[1]
+	files := map[string]string{
+		".bashrc":  "alias ll='ls -l'\n\n" + sdkmanLine + "\n\n" + appendManagedBlock("", "export A=1\n"),
+		".zshrc":   "setopt autocd\n",
+		".profile": "export EDITOR=vi\n\n" + sdkmanLine + "\n\n" + nvmLine + "\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(home, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, err := PlanReset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shellItems []ResetItem
+	for _, item := range items {
+		if item.Kind == ResetShell {
+			shellItems = append(shellItems, item)
+		}
+	}
+	if len(shellItems) != 2 {
+		t.Fatalf("got shell items %v, want one for .bashrc and one for .profile", shellItems)
+	}
+	for _, item := range shellItems {
+		if !strings.Contains(item.Description, "unmarked") {
+			t.Errorf("description %q does not mention the legacy lines", item.Description)
+		}
+		if err := item.Remove(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		".bashrc":  "alias ll='ls -l'\n",
+		".zshrc":   "setopt autocd\n",
+		".profile": "export EDITOR=vi\n",
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(home, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+		backups, _ := filepath.Glob(filepath.Join(home, name+".sdk-ui-go.*.bak"))
+		if changed := content != files[name]; changed != (len(backups) == 1) {
+			t.Errorf("%s has backups %v", name, backups)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	RetentionOff    = "off"
+	RetentionNotify = "notify"
+	RetentionAuto   = "auto"
+)
+
+// RetentionPolicy keeps the default version, the newest KeepPatches
+// versions of every line (see VersionLine), the Pinned versions and the
+// versions registered projects use. Everything else installed is pruned.
+type RetentionPolicy struct {
+	KeepPatches int      `json:"keep_patches"`
+	Pinned      []string `json:"pinned"`
+}
+
+// PruneCandidate is an installed version a retention policy would remove.
+type PruneCandidate struct {
+	Tool    string
+	Version string
+	Reason  string
+}
+
+func (p PruneCandidate) String() string {
+	return p.Tool + " " + p.Version + " (" + p.Reason + ")"
+}
+
+// PlanRetention lists what the configured policies would remove, as a dry
+// run; nothing is touched. The default version of a tool is never part of
+// the plan.
+func PlanRetention(cfg Config) ([]PruneCandidate, error) {
+	referenced, err := ProjectReferences(cfg.Projects)
+	if err != nil {
+		return nil, err
+	}
+	var tools []string
+	for tool := range cfg.Retention {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+
+	var plan []PruneCandidate
+	for _, tool := range tools {
+		policy := cfg.Retention[tool]
+		var installed []Candidate
+		if tool == NodeWatchKey {
+			installed, err = LocalNodeVersions()
+		} else {
+			installed, err = LocalCandidateVersions(tool)
+		}
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, planTool(tool, policy, installed, referenced[tool])...)
+	}
+	return plan, nil
+}
+
+func planTool(tool string, policy RetentionPolicy, installed []Candidate, referenced map[string]bool) []PruneCandidate {
+	lines := make(map[string][]Candidate)
+	for _, c := range installed {
+		if c.Custom || MajorVersion(c.Identifier) == 0 {
+			// Linked-in and unparsable versions are left to the user.
+			continue
+		}
+		lines[VersionLine(c.Identifier)] = append(lines[VersionLine(c.Identifier)], c)
+	}
+	var plan []PruneCandidate
+	for line, versions := range lines {
+		kept := 0
+		for _, c := range SortCandidates(versions) {
+			switch {
+			case c.Use, referenced[c.Identifier], isPinned(policy.Pinned, c.Identifier):
+				continue
+			case kept < policy.KeepPatches:
+				kept++
+				continue
+			}
+			plan = append(plan, PruneCandidate{
+				Tool:    tool,
+				Version: c.Identifier,
+				Reason:  fmt.Sprintf("older than the newest %d of %s", policy.KeepPatches, line),
+			})
+		}
+	}
+	sort.Slice(plan, func(i, j int) bool { return plan[i].Version < plan[j].Version })
+	return plan
+}
+
+// isPinned matches exact versions as well as prefixes such as "17" or
+// "21.0", so a whole line can be pinned.
+func isPinned(pinned []string, version string) bool {
+	for _, pin := range pinned {
+		pin = strings.TrimPrefix(pin, "v")
+		v := strings.TrimPrefix(version, "v")
+		if v == pin || strings.HasPrefix(v, pin+".") || strings.HasPrefix(v, pin+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// ProjectReferences maps tool to the installed versions the .sdkmanrc and
+// .nvmrc files of projects ask for. Node specs such as "20" or "lts/*" are
+// resolved against the installed versions.
+func ProjectReferences(projects []string) (map[string]map[string]bool, error) {
+	referenced := make(map[string]map[string]bool)
+	var nodeVersions []string
+	if versions, err := LocalNodeVersions(); err == nil {
+		for _, v := range versions {
+			nodeVersions = append(nodeVersions, v.Identifier)
+		}
+	}
+	for _, project := range projects {
+		pinned, err := FindProjectVersions(project)
+		if err != nil {
+			return nil, fmt.Errorf("reading project %s: %w", project, err)
+		}
+		for _, p := range pinned {
+			version := p.Version
+			if p.Tool == NodeWatchKey {
+				version = ResolveNodeVersion(p.Version, nodeVersions)
+			}
+			if referenced[p.Tool] == nil {
+				referenced[p.Tool] = make(map[string]bool)
+			}
+			referenced[p.Tool][version] = true
+		}
+	}
+	return referenced, nil
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func prunedVersions(plan []PruneCandidate) []string {
+	var versions []string
+	for _, p := range plan {
+		versions = append(versions, p.Version)
+	}
+	return versions
+}
+
+func TestPlanToolSortsTwoPartVersionsNumerically(t *testing.T) {
+	installed := []Candidate{
+		{Identifier: "8.10", Install: true},
+		{Identifier: "8.9", Install: true},
+		{Identifier: "8.2.1", Install: true},
+	}
+	plan := planTool("gradle", RetentionPolicy{KeepPatches: 1}, installed, nil)
+	if got, want := prunedVersions(plan), []string{"8.2.1", "8.9"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pruned %v, want %v", got, want)
+	}
+}
+
+func TestPlanToolKeepsNewestPerLine(t *testing.T) {
+	installed := []Candidate{
+		{Identifier: "21.0.4-tem", Install: true},
+		{Identifier: "21.0.3-tem", Install: true},
+		{Identifier: "21.0.2-tem", Install: true},
+		{Identifier: "21.0.4.fx-zulu", Install: true},
+		{Identifier: "17.0.9-tem", Install: true},
+		{Identifier: "17.0.10-tem", Install: true},
+	}
+	plan := planTool("java", RetentionPolicy{KeepPatches: 2}, installed, nil)
+	if got, want := prunedVersions(plan), []string{"21.0.2-tem"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pruned %v, want %v", got, want)
+	}
+	plan = planTool("java", RetentionPolicy{KeepPatches: 1}, installed, nil)
+	if got, want := prunedVersions(plan), []string{"17.0.9-tem", "21.0.2-tem", "21.0.3-tem"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pruned %v, want %v", got, want)
+	}
+}
+
+func TestPlanToolKeepsDefaultPinnedReferencedAndCustom(t *testing.T) {
+	installed := []Candidate{
+		{Identifier: "v22.9.0", Install: true},
+		{Identifier: "v22.8.0", Install: true, Use: true},
+		{Identifier: "v22.7.0", Install: true},
+		{Identifier: "v22.6.0", Install: true},
+		{Identifier: "v20.10.0", Install: true},
+		{Identifier: "v20.9.0", Install: true},
+		{Identifier: "local-build", Install: true, Custom: true},
+	}
+	policy := RetentionPolicy{KeepPatches: 1, Pinned: []string{"20"}}
+	referenced := map[string]bool{"v22.7.0": true}
+	plan := planTool(NodeWatchKey, policy, installed, referenced)
+	if got, want := prunedVersions(plan), []string{"v22.6.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pruned %v, want %v", got, want)
+	}
+}
+
+func TestIsPinned(t *testing.T) {
+	tests := []struct {
+		pin     string
+		version string
+		want    bool
+	}{
+		{"17", "17.0.10-tem", true},
+		{"21.0", "21.0.4-tem", true},
+		{"21.0.4-tem", "21.0.4-tem", true},
+		{"20", "v20.11.1", true},
+		{"v20", "v20.11.1", true},
+		{"2", "21.0.4-tem", false},
+		{"8.1", "8.10", false},
+	}
+	for _, tt := range tests {
+		if got := isPinned([]string{tt.pin}, tt.version); got != tt.want {
+			t.Errorf("isPinned(%q, %q) = %v, want %v", tt.pin, tt.version, got, tt.want)
+		}
+	}
+}
@@ -2,8 +2,8 @@ package internal
 
 import (
 	"fmt"
-	"github.com/gen2brain/beeep"
 	"github.com/ncruces/zenity"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,14 +12,11 @@ import (
 	"strings"
 )
 
-var defaultSDKManEnv = `export SDKMAN_DIR="$HOME/.sdkman" && [[ -s "$HOME/.sdkman/bin/sdkman-init.sh" ]] && source "$HOME/.sdkman/bin/sdkman-init.sh"`
-
-func JavaVersionList(scriptPath string) []Candidate {
+func JavaVersionList(scriptPath string) ([]Candidate, error) {
 	var javaVersions []Candidate
 	out, err := CommandExec([]string{"source " + scriptPath + " && sdk list java"})
 	if err != nil {
-		fmt.Println("Error running command:", err)
-		return javaVersions
+		return javaVersions, err
 	}
 	lines := strings.Split(out, "\n")
 	for _, line := range lines {
@@ -38,12 +35,15 @@ func JavaVersionList(scriptPath string) []Candidate {
 			javaVersions = append(javaVersions, versionInfo)
 		}
 	}
-	return javaVersions
+	return javaVersions, nil
 }
 
-func OtherVersionList(candidate string, scriptPath string) []Candidate {
+func OtherVersionList(candidate string, scriptPath string) ([]Candidate, error) {
 
-	out, _ := CommandExec([]string{"source " + scriptPath + " && sdk list " + candidate})
+	out, err := CommandExec([]string{"source " + scriptPath + " && sdk list " + candidate})
+	if err != nil {
+		return nil, err
+	}
 	lines := strings.Split(out, "\n")
 	re := regexp.MustCompile(`([>*\s]*)\s*(\d+\.\d+(\.\d+)?(-beta-\d+)?(_\d+)?(-\w+)?(-\w+)?)`)
 
@@ -73,16 +73,25 @@ func OtherVersionList(candidate string, scriptPath string) []Candidate {
 		}
 	}
 
-	return versionInfos
+	return versionInfos, nil
 }
 
-func OpenCandidateFolder(candidate string, version, scriptPath string) {
-	out, _ := CommandExec([]string{"source " + scriptPath + " && sdk home " + candidate + " " + version})
-	openFolder(strings.TrimSpace(out))
+func OpenCandidateFolder(candidate string, version, scriptPath string) error {
+	if home := LocalCandidateHome(candidate, version); FileExists(home) {
+		return OpenPath(home)
+	}
+	out, err := CommandExec([]string{"source " + scriptPath + " && sdk home " + candidate + " " + version})
+	if err != nil {
+		return err
+	}
+	return OpenPath(strings.TrimSpace(out))
 }
 
-func CandidateList(scriptPath string) []string {
-	out, _ := CommandExec([]string{"source " + scriptPath + " && sdk list"})
+func CandidateList(scriptPath string) ([]string, error) {
+	out, err := CommandExec([]string{"source " + scriptPath + " && sdk list"})
+	if err != nil {
+		return nil, err
+	}
 	lines := strings.Split(out, "\n")
 	re := regexp.MustCompile(`\$ sdk install (\S+)`)
 
@@ -96,26 +105,39 @@ func CandidateList(scriptPath string) []string {
 			installCommands = append(installCommands, matches[1])
 		}
 	}
-	return installCommands
+	return installCommands, nil
+}
+
+func UseCandidate(candidate string, version string, scriptPath string) (string, error) {
+	slog.Info("installing candidate", "candidate", candidate, "version", version)
+	out, err := CommandExec([]string{"source " + scriptPath + " && sdk install " + candidate + " " + version + " && sdk default " + candidate + " " + version})
+	if err == nil {
+		slog.Info("installed candidate", "candidate", candidate, "version", version)
+	}
+	return out, err
 }
 
-func UseCandidate(candidate string, version string, scriptPath string) {
-	fmt.Println("Installing", candidate, version)
-	_, err := CommandExec([]string{"source " + scriptPath + " && sdk install " + candidate + " " + version + " && sdk default " + candidate + " " + version})
+// InstallCandidate installs version without making it the default, for
+// projects that pin it in .sdkmanrc.
+func InstallCandidate(candidate string, version string, scriptPath string) (string, error) {
+	slog.Info("installing candidate", "candidate", candidate, "version", version)
+	out, err := CommandExec([]string{"source " + scriptPath + " && echo n | sdk install " + candidate + " " + version})
 	if err == nil {
-		fmt.Println("Installed", candidate, version)
+		slog.Info("installed candidate", "candidate", candidate, "version", version)
 	}
+	return out, err
 }
 
-func UninstallCandidate(candidate string, version string, scriptPath string) {
-	fmt.Println("UnInstalling", candidate, version)
-	_, err := CommandExec([]string{"source " + scriptPath + " && sdk uninstall " + candidate + " " + version})
+func UninstallCandidate(candidate string, version string, scriptPath string) (string, error) {
+	slog.Info("uninstalling candidate", "candidate", candidate, "version", version)
+	out, err := CommandExec([]string{"source " + scriptPath + " && sdk uninstall " + candidate + " " + version})
 	if err == nil {
-		fmt.Println("UnInstalled", candidate, version)
+		slog.Info("uninstalled candidate", "candidate", candidate, "version", version)
 	}
+	return out, err
 }
 
-func openFolder(path string) error {
+func OpenPath(path string) error {
 	var cmd *exec.Cmd
 
 	switch runtime.GOOS {
@@ -139,78 +161,159 @@ func FileExists(path string) bool {
 	return true
 }
 
+// InstallSDKMan bootstraps SDKMan from the configured, verified installer
+// when SDKMAN_DIR does not exist yet.
 func InstallSDKMan() error {
-	homeDir := os.Getenv("HOME")
-	if homeDir == "" {
-		return fmt.Errorf("HOME environment variable is not set")
-	}
-	EnvWrite(defaultSDKManEnv, "sdkman", "export SDKMAN_DIR")
-	sdkManPath := filepath.Join(homeDir, ".sdkman")
-	if FileExists(sdkManPath) {
-		fmt.Println("SDKMan already installed")
+	if FileExists(SDKManDir()) {
+		slog.Info("SDKMan already installed")
 		return nil
 	}
-	beeep.Notify("SDKMan Installation", "SDKMan is not installed, Installing SDKMan", "")
-	output, err := CommandExec([]string{"curl -s \"https://get.sdkman.io\" | bash"})
+	installer := CurrentConfig().Bootstrap.SDKMan
+	if installer.SHA256 == "" && installer.Source == DefaultSDKManInstaller {
+		installer.SHA256 = SDKManInstallerSHA256[installer.Version]
+	}
+	if installer.SHA256 != "" {
+		Notify("SDKMan Installation", "SDKMan is not installed, Installing SDKMan")
+	}
+	err := bootstrap("SDKMan", SDKManDir(), installer, func(path string) error {
+		if isZip(path) {
+			return installSDKManRelease(path, installer.Version)
+		}
+		_, err := CommandExecCombined([]string{"bash " + shellQuote(path)})
+		return err
+	}, func() string {
+		version, _ := SDKManScriptVersion()
+		return version
+	})
 	if err != nil {
-		fmt.Printf("Error running command: %v\nOutput: %s\n", err, string(output))
 		return err
 	}
-	fmt.Println("SDKMan installed successfully")
-	beeep.Notify("SDKMan Installation", "SDKMan installed successfully", "")
+	slog.Info("SDKMan installed successfully")
+	Notify("SDKMan Installation", "SDKMan installed successfully")
 	return nil
 }
 
-func SDKManVersion(scriptPath string) string {
-	cmd := exec.Command("bash", "-c", "source "+scriptPath+" && sdk version")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		fmt.Printf("Error running command: %v\nOutput: %s\n", err, string(output))
-		return ""
+// sdkmanConfig is the etc/config the SDKMan get script writes, minus the
+// shell rc updates the app handles itself.
+const sdkmanConfig = `sdkman_auto_answer=false
+sdkman_auto_complete=true
+sdkman_auto_env=false
+sdkman_auto_update=true
+sdkman_beta_channel=false
+sdkman_checksum_enable=true
+sdkman_colour_enable=true
+sdkman_curl_connect_timeout=7
+sdkman_curl_max_time=10
+sdkman_debug_mode=false
+sdkman_insecure_ssl=false
+sdkman_selfupdate_feature=true
+`
+
+// installSDKManRelease lays out SDKMAN_DIR from a release zip the way the
+// get script does, then fetches the candidate list. A failed `sdk update`
+// only means the list is fetched on first use.
+func installSDKManRelease(path string, version string) error {
+	dir := SDKManDir()
+	for _, sub := range []string{"bin", "src", "tmp", "ext", "etc", "var", "candidates"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return err
+		}
+	}
+	if err := extractZip(path, dir); err != nil {
+		return err
+	}
+	if !FileExists(filepath.Join(dir, "etc", "config")) {
+		if err := os.WriteFile(filepath.Join(dir, "etc", "config"), []byte(sdkmanConfig), 0644); err != nil {
+			return err
+		}
 	}
+	if err := os.WriteFile(filepath.Join(dir, "var", "version"), []byte(version+"\n"), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "var", "platform"), []byte(sdkmanPlatform(runtime.GOOS, runtime.GOARCH)+"\n"), 0644); err != nil {
+		return err
+	}
+	if !FileExists(filepath.Join(dir, "var", "candidates")) {
+		if err := os.WriteFile(filepath.Join(dir, "var", "candidates"), nil, 0644); err != nil {
+			return err
+		}
+	}
+	if out, err := SDKManUpdate(shellQuote(SDKManInitScript())); err != nil {
+		slog.Warn("fetching the SDKMan candidate list", "err", err, "output", out)
+	}
+	return nil
+}
 
-	return strings.TrimSpace(string(output))
+// sdkmanPlatform names goos/goarch the way the SDKMan get script names the
+// output of uname, e.g. darwinarm64.
+func sdkmanPlatform(goos string, goarch string) string {
+	platforms := map[string]string{
+		"darwin/amd64":  "darwinx64",
+		"darwin/arm64":  "darwinarm64",
+		"linux/386":     "linuxx32",
+		"linux/amd64":   "linuxx64",
+		"linux/arm":     "linuxarm32hf",
+		"linux/arm64":   "linuxarm64",
+		"windows/amd64": "windowsx64",
+	}
+	if platform, ok := platforms[goos+"/"+goarch]; ok {
+		return platform
+	}
+	return "exotic"
 }
 
-func SDKManUpdate(scriptPath string) error {
-	cmd := exec.Command("bash", "-c", "source "+scriptPath+" && sdk update")
-	output, err := cmd.CombinedOutput()
+func SDKManVersion(scriptPath string) (string, error) {
+	output, err := CommandExecCombined([]string{"source " + scriptPath + " && sdk version"})
 	if err != nil {
-		fmt.Printf("Error running command: %v\nOutput: %s\n", err, string(output))
-		return err
+		return "", err
 	}
 
-	return nil
+	return strings.TrimSpace(output), nil
+}
+
+// SDKManUpdate refreshes SDKMan's candidate metadata (`sdk update`); it does
+// not upgrade SDKMan itself, see SDKManSelfUpdate.
+func SDKManUpdate(scriptPath string) (string, error) {
+	return CommandExecCombined([]string{"source " + scriptPath + " && sdk update"})
 }
 
-func AddCustomCandidate(candidate string, scriptPath string) string {
+// SDKManSelfUpdate upgrades the SDKMan scripts to the latest release.
+func SDKManSelfUpdate(scriptPath string) (string, error) {
+	slog.Info("upgrading SDKMan")
+	return CommandExecCombined([]string{"source " + scriptPath + " && sdk selfupdate"})
+}
+
+// SDKManScriptVersion is the installed SDKMan release, as recorded in
+// $SDKMAN_DIR/var/version.
+func SDKManScriptVersion() (string, error) {
+	version, err := os.ReadFile(filepath.Join(SDKManDir(), "var", "version"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(version)), nil
+}
+
+func AddCustomCandidate(candidate string, scriptPath string) (string, error) {
 	id, err := zenity.Entry(`Please enter your custom ID for your `+candidate, zenity.Title("ID Input"))
 	if err != nil {
-		err := zenity.Warning("No ID entered or an error occurred:")
-		if err != nil {
-			return ""
-		}
-		return ""
+		zenity.Warning("No ID entered or an error occurred:")
+		return "", nil
 	}
 	folder, err := zenity.Entry(`Please enter your absolute home path for your `+candidate, zenity.Title("Folder Input"))
 	if err != nil {
-		err := zenity.Warning("No folder selected or an error occurred:")
-		fmt.Println("No folder selected or an error occurred:", err)
-		return ""
+		zenity.Warning("No folder selected or an error occurred:")
+		slog.Info("no folder selected", "err", err)
+		return "", nil
 	}
 	//check folder exists
 	if !FileExists(folder) {
-		err := zenity.Warning("Folder does not exist")
-		if err != nil {
-			return ""
-		}
-		return ""
+		zenity.Warning("Folder does not exist")
+		return "", nil
 	}
 	_, err = CommandExec([]string{"source " + scriptPath + " && sdk install " + candidate + " " + id + " " + folder})
 	if err != nil {
-		zenity.Warning("Installed Failed for " + candidate + id + "with folder " + folder)
-		fmt.Println("Installed Failed for " + candidate + id + "with folder " + folder)
-		return ""
+		slog.Error("installing local candidate", "candidate", candidate, "id", id, "folder", folder, "err", err)
+		return "", err
 	}
-	return id
+	return id, nil
 }
@@ -1,12 +1,16 @@
 package internal
 
 import (
-	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"github.com/gen2brain/beeep"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -17,53 +21,79 @@ type Candidate struct {
 	Install    bool
 	Identifier string
 	Custom     bool
+	// LTS is the codename of the Node LTS line the version belongs to.
+	LTS string `json:",omitempty"`
 }
 
-func parseVersion(identifier string) (int, int, int, error) {
-	re := regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
-	matches := re.FindStringSubmatch(identifier)
-	if matches == nil || len(matches) != 4 {
-		return 0, 0, 0, fmt.Errorf("invalid version format")
+var versionNumbersPattern = regexp.MustCompile(`^v?(\d+(?:\.\d+)*)`)
+
+// versionNumbers are the leading numeric parts of an identifier, e.g. 21 0 3
+// for 21.0.3-tem or 8 10 for Gradle's 8.10, or nil when it has none.
+func versionNumbers(identifier string) []int {
+	matches := versionNumbersPattern.FindStringSubmatch(identifier)
+	if matches == nil {
+		return nil
 	}
-	major, err := strconv.Atoi(matches[1])
-	if err != nil {
-		return 0, 0, 0, err
+	var numbers []int
+	for _, part := range strings.Split(matches[1], ".") {
+		n, _ := strconv.Atoi(part)
+		numbers = append(numbers, n)
 	}
-	minor, err := strconv.Atoi(matches[2])
-	if err != nil {
-		return 0, 0, 0, err
+	return numbers
+}
+
+// compareVersions orders identifiers by their numeric parts, so 8.10 comes
+// after 8.9 and 8.10.1 after 8.10. It returns 0 when the numbers are equal
+// or either identifier has none.
+func compareVersions(a string, b string) int {
+	return compareNumbers(versionNumbers(a), versionNumbers(b))
+}
+
+func compareNumbers(a []int, b []int) int {
+	if a == nil || b == nil {
+		return 0
 	}
-	patch, err := strconv.Atoi(matches[3])
-	if err != nil {
-		return 0, 0, 0, err
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] > b[i] {
+				return 1
+			}
+			return -1
+		}
+	}
+	switch {
+	case len(a) > len(b):
+		return 1
+	case len(a) < len(b):
+		return -1
 	}
-	return major, minor, patch, nil
+	return 0
 }
 
+// SortCandidates sorts newest first by version number. Identifiers without
+// one, such as custom ones, come last. Each identifier is parsed once, since
+// long lists are sorted on every menu load.
 func SortCandidates(candidates []Candidate) []Candidate {
-	versionRegex := regexp.MustCompile(`\d+\.\d+\.\d+`)
+	numbers := make(map[string][]int, len(candidates))
+	for _, c := range candidates {
+		numbers[c.Identifier] = versionNumbers(c.Identifier)
+	}
 
 	sort.Slice(candidates, func(i, j int) bool {
-		iIsVersion := versionRegex.MatchString(candidates[i].Identifier)
-		jIsVersion := versionRegex.MatchString(candidates[j].Identifier)
-
-		if iIsVersion && jIsVersion {
-			iMajor, iMinor, iPatch, _ := parseVersion(candidates[i].Identifier)
-			jMajor, jMinor, jPatch, _ := parseVersion(candidates[j].Identifier)
+		iNumbers := numbers[candidates[i].Identifier]
+		jNumbers := numbers[candidates[j].Identifier]
 
-			if iMajor != jMajor {
-				return iMajor > jMajor
-			}
-			if iMinor != jMinor {
-				return iMinor > jMinor
+		if iNumbers != nil && jNumbers != nil {
+			if c := compareNumbers(iNumbers, jNumbers); c != 0 {
+				return c > 0
 			}
-			return iPatch > jPatch
+			return candidates[i].Identifier > candidates[j].Identifier
 		}
 
-		if iIsVersion {
+		if iNumbers != nil {
 			return true
 		}
-		if jIsVersion {
+		if jNumbers != nil {
 			return false
 		}
 
@@ -73,66 +103,169 @@ func SortCandidates(candidates []Candidate) []Candidate {
 	return candidates
 }
 
-func CommandExec(commands []string) (string, error) {
-	cmd := exec.Command("bash", "-c", strings.Join(commands, " "))
-	out, err := cmd.Output()
-	if err != nil {
-		fmt.Println("error " + strings.Join(commands, " "))
-		fmt.Println("Error running command:", err)
-		return "", err
+// IsJavaLTS reports whether a Java identifier such as 21.0.3-tem belongs to
+// a long-term support line: 8, 11, 17 and every fourth release from 21 on.
+func IsJavaLTS(identifier string) bool {
+	major := JavaVersion(identifier)
+	switch {
+	case major == 8 || major == 11 || major == 17:
+		return true
+	case major >= 21:
+		return (major-21)%4 == 0
 	}
-	return string(out), nil
+	return false
 }
 
-func containsEnv(filePath string, env string) bool {
-	file, err := os.Open(filePath)
-	if err != nil {
-		fmt.Printf("Error opening %s: %v\n", filePath, err)
-		return false
+var javaReleasePattern = regexp.MustCompile(`\.r(\d+)(?:[.-]|$)`)
+
+// JavaVersion is the Java release a Java identifier is built on: the .rNN
+// qualifier of GraalVM and Mandrel builds, e.g. 21 for 23.1.4.r21-mandrel
+// or 17 for 22.3.r17-grl, and the major version otherwise.
+func JavaVersion(identifier string) int {
+	if matches := javaReleasePattern.FindStringSubmatch(identifier); matches != nil {
+		release, _ := strconv.Atoi(matches[1])
+		return release
 	}
-	defer file.Close()
+	return MajorVersion(identifier)
+}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		if strings.Contains(scanner.Text(), env) {
-			return true
+// MajorVersion is the leading number of an identifier such as 21.0.3-tem or
+// v20.11.1, or 0 when there is none.
+func MajorVersion(identifier string) int {
+	re := regexp.MustCompile(`^v?(\d+)`)
+	matches := re.FindStringSubmatch(identifier)
+	if matches == nil {
+		return 0
+	}
+	major, _ := strconv.Atoi(matches[1])
+	return major
+}
+
+// VersionLine groups identifiers that only differ in their minor or patch
+// version: the major version plus the qualifier and vendor suffix SDKMan
+// uses for Java, e.g. 21-tem for 21.0.3-tem, 21.fx-zulu for 21.0.4.fx-zulu,
+// 23.r21-mandrel for 23.1.4.r21-mandrel, or 20 for Node's v20.11.1. Numeric
+// parts after the qualifier, such as early access build numbers, are left
+// out.
+func VersionLine(identifier string) string {
+	version, vendor := identifier, ""
+	if i := strings.LastIndex(identifier, "-"); i >= 0 {
+		version, vendor = identifier[:i], identifier[i:]
+	}
+	line := strconv.Itoa(MajorVersion(identifier))
+	for _, part := range strings.Split(version, ".") {
+		if _, err := strconv.Atoi(strings.TrimPrefix(part, "v")); err != nil {
+			line += "." + part
+		}
+	}
+	return line + vendor
+}
+
+// FilterCandidates applies one of the Filter* settings to the versions of
+// tool. The LTS filter knows about Java lines and Node's LTS codenames, and
+// always keeps installed versions so the current default never disappears
+// from the menu.
+func FilterCandidates(tool string, candidates []Candidate, filter string) []Candidate {
+	if filter == FilterAll || filter == "" {
+		return candidates
+	}
+	var filtered []Candidate
+	for _, c := range candidates {
+		switch filter {
+		case FilterInstalled:
+			if c.Install {
+				filtered = append(filtered, c)
+			}
+		case FilterLTS:
+			switch {
+			case c.Install:
+			case strings.EqualFold(tool, "java") && !IsJavaLTS(c.Identifier):
+				continue
+			case tool == NodeWatchKey && c.LTS == "":
+				continue
+			}
+			filtered = append(filtered, c)
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("Error reading %s: %v\n", filePath, err)
+	return filtered
+}
+
+func CommandExec(commands []string) (string, error) {
+	command := strings.Join(commands, " ")
+	cmd := exec.Command("bash", "-c", command)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		slog.Error("running command", "command", command, "err", err, "stderr", stderr.String())
+		return stdout.String(), newCommandError(command, stdout.String(), stderr.String(), err)
 	}
-	return false
+	return stdout.String(), nil
 }
 
-func EnvWrite(defaultEnvScript string, provider string, env string) {
-	shellConfigFiles := []string{".bashrc", ".zshrc", ".profile"}
-	homeDir, err := os.UserHomeDir()
+// CommandExecCombined is CommandExec for tools that report progress and
+// errors on stdout and stderr interleaved.
+func CommandExecCombined(commands []string) (string, error) {
+	command := strings.Join(commands, " ")
+	cmd := exec.Command("bash", "-c", command)
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		fmt.Printf("Error getting user home directory: %v\n", err)
+		slog.Error("running command", "command", command, "err", err, "output", string(output))
+		return string(output), newCommandError(command, string(output), "", err)
+	}
+	return string(output), nil
+}
+
+// Notify shows a desktop notification unless they are turned off in the
+// config.
+func Notify(title string, message string) {
+	if !CurrentConfig().Notifications {
 		return
 	}
+	if err := beeep.Notify(title, message, ""); err != nil {
+		slog.Warn("showing notification", "title", title, "err", err)
+	}
+}
 
-	for _, configFile := range shellConfigFiles {
-		configFilePath := filepath.Join(homeDir, configFile)
-		if _, err := os.Stat(configFilePath); err == nil {
-			file, err := os.OpenFile(configFilePath, os.O_APPEND|os.O_WRONLY, 0644)
-			if err != nil {
-				fmt.Printf("Error opening %s: %v\n", configFilePath, err)
-				continue
-			}
-			defer file.Close()
-
-			if !containsEnv(configFilePath, env) {
-				if _, err := file.WriteString("\n" + defaultEnvScript + "\n"); err != nil {
-					fmt.Printf("Error writing to %s: %v\n", configFilePath, err)
-				} else {
-					fmt.Printf("Updated %s\n", configFilePath)
-				}
-			} else {
-				fmt.Printf("%s already contains"+provider+" settings, skipping...\n", configFilePath)
-			}
+func CopyToClipboard(text string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("pbcopy")
+	case "windows":
+		cmd = exec.Command("clip")
+	case "linux":
+		if _, err := exec.LookPath("wl-copy"); err == nil {
+			cmd = exec.Command("wl-copy")
 		} else {
-			fmt.Printf("%s does not exist, skipping...\n", configFilePath)
+			cmd = exec.Command("xclip", "-selection", "clipboard")
 		}
+	default:
+		return fmt.Errorf("unsupported platform")
+	}
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
+
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func AppConfigDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(configDir, "sdk-ui-go")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
 	}
+	return dir, nil
 }
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVersionLine(t *testing.T) {
+	tests := []struct {
+		identifier string
+		want       string
+	}{
+		{"21.0.3-tem", "21-tem"},
+		{"17.0.12-amzn", "17-amzn"},
+		{"8.0.422-zulu", "8-zulu"},
+		{"21.0.4.fx-zulu", "21.fx-zulu"},
+		{"21.0.4.crac-zulu", "21.crac-zulu"},
+		{"17.0.12.crac-librca", "17.crac-librca"},
+		{"11.0.24.fx-librca", "11.fx-librca"},
+		{"23.1.4.r21-mandrel", "23.r21-mandrel"},
+		{"22.3.r17-grl", "22.r17-grl"},
+		{"24.1.1.r23-nik", "24.r23-nik"},
+		{"21.0.2-graalce", "21-graalce"},
+		{"24.ea.14-open", "24.ea-open"},
+		{"v20.11.1", "20"},
+		{"8.10", "8"},
+		{"3.9.9", "3"},
+	}
+	for _, tt := range tests {
+		if got := VersionLine(tt.identifier); got != tt.want {
+			t.Errorf("VersionLine(%q) = %q, want %q", tt.identifier, got, tt.want)
+		}
+	}
+}
+
+func TestSortCandidates(t *testing.T) {
+	candidates := []Candidate{
+		{Identifier: "8.9"},
+		{Identifier: "custom"},
+		{Identifier: "8.10"},
+		{Identifier: "8.10.1"},
+		{Identifier: "7.6.4"},
+		{Identifier: "22.3.r17-grl"},
+	}
+	var got []string
+	for _, c := range SortCandidates(candidates) {
+		got = append(got, c.Identifier)
+	}
+	want := []string{"22.3.r17-grl", "8.10.1", "8.10", "8.9", "7.6.4", "custom"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortCandidates = %v, want %v", got, want)
+	}
+}
+
+func TestJavaVersion(t *testing.T) {
+	tests := []struct {
+		identifier string
+		want       int
+	}{
+		{"21.0.3-tem", 21},
+		{"8.0.422-zulu", 8},
+		{"21.0.4.fx-zulu", 21},
+		{"23.1.4.r21-mandrel", 21},
+		{"22.3.r17-grl", 17},
+		{"24.1.1.r23-nik", 23},
+		{"21.0.2-graalce", 21},
+	}
+	for _, tt := range tests {
+		if got := JavaVersion(tt.identifier); got != tt.want {
+			t.Errorf("JavaVersion(%q) = %d, want %d", tt.identifier, got, tt.want)
+		}
+	}
+}
+
+func TestFilterCandidatesJavaLTS(t *testing.T) {
+	candidates := []Candidate{
+		{Identifier: "25.0.1-tem"},
+		{Identifier: "24.0.2-tem"},
+		{Identifier: "23.1.4.r21-mandrel"},
+		{Identifier: "24.1.1.r23-nik"},
+		{Identifier: "22.3.r17-grl"},
+		{Identifier: "22.0.2-oracle", Install: true},
+	}
+	var got []string
+	for _, c := range FilterCandidates("java", candidates, FilterLTS) {
+		got = append(got, c.Identifier)
+	}
+	want := []string{"25.0.1-tem", "23.1.4.r21-mandrel", "22.3.r17-grl", "22.0.2-oracle"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FilterCandidates = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,396 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	shellBlockStart = "# >>> sdk-ui-go >>>"
+	shellBlockEnd   = "# <<< sdk-ui-go <<<"
+	shellBlockNote  = "# Managed by SDK UI. Edit the app settings instead; this block is rewritten."
+
+	ShellIntegrationAsk = "ask"
+	ShellIntegrationYes = "yes"
+	ShellIntegrationNo  = "no"
+)
+
+// legacyShellLines are the lines earlier versions appended to .bashrc,
+// .zshrc and .profile without markers. They are folded into the managed
+// block when found, and reset offers to remove them.
+var legacyShellLines = []string{
+	`export SDKMAN_DIR="$HOME/.sdkman" && [[ -s "$HOME/.sdkman/bin/sdkman-init.sh" ]] && source "$HOME/.sdkman/bin/sdkman-init.sh"`,
+	`export NVM_DIR="$HOME/.nvm"; [ -s "$NVM_DIR/nvm.sh" ] && \. "$NVM_DIR/nvm.sh"; [ -s "$NVM_DIR/bash_completion" ] && \. "$NVM_DIR/bash_completion"`,
+}
+
+type Shell struct {
+	Name   string
+	RCFile string
+	// Dedicated files such as fish's conf.d snippet belong to the app
+	// entirely and are deleted instead of left empty.
+	Dedicated bool
+	// Previous lists rc files older versions wrote the block to; it is
+	// removed from them when the shell is set up.
+	Previous []string
+	// snippet renders the block body; sdkman and nvm are false when the rc
+	// file already sets that tool up outside the managed block.
+	snippet func(sdkman bool, nvm bool) string
+}
+
+// ShellChange is the pending rewrite of one rc file.
+type ShellChange struct {
+	Shell   Shell
+	Before  string
+	After   string
+	Removed bool
+}
+
+func (c ShellChange) Changed() bool {
+	return c.Before != c.After
+}
+
+// BlockOnly reports whether the change only rewrites or removes the managed
+// block of a file that already has one, leaving the user's own lines alone.
+func (c ShellChange) BlockOnly() bool {
+	return strings.Contains(c.Before, shellBlockStart) && stripManagedBlock(c.Before) == stripManagedBlock(c.After)
+}
+
+// Diff renders the change as removed and added lines for the consent dialog.
+func (c ShellChange) Diff() string {
+	var b strings.Builder
+	b.WriteString("--- " + c.Shell.RCFile + "\n+++ " + c.Shell.RCFile + "\n")
+	b.WriteString(lineDiff(splitLines(c.Before), splitLines(c.After)))
+	return b.String()
+}
+
+// DetectShells returns the shells the user has an rc file for, plus the login
+// shell from $SHELL even if its rc file does not exist yet.
+func DetectShells() []Shell {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		slog.Error("getting user home directory", "err", err)
+		return nil
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	nuConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		nuConfigDir = configHome
+	}
+	known := []Shell{
+		{Name: "bash", RCFile: filepath.Join(homeDir, ".bashrc"), snippet: posixShellSnippet},
+		{Name: "zsh", RCFile: filepath.Join(homeDir, ".zshrc"), snippet: posixShellSnippet},
+		{
+			Name:      "fish",
+			RCFile:    filepath.Join(configHome, "fish", "conf.d", "sdk-ui-go.fish"),
+			Dedicated: true,
+			Previous:  []string{filepath.Join(configHome, "fish", "config.fish")},
+			snippet:   fishShellSnippet,
+		},
+		{Name: "nu", RCFile: filepath.Join(nuConfigDir, "nushell", "env.nu"), snippet: nushellSnippet},
+	}
+	loginShell := filepath.Base(os.Getenv("SHELL"))
+	var shells []Shell
+	for _, shell := range known {
+		// A dedicated file only exists once we wrote it, so look at the
+		// shell's own config directory instead.
+		marker := shell.RCFile
+		if shell.Dedicated {
+			marker = filepath.Dir(filepath.Dir(shell.RCFile))
+		}
+		if FileExists(marker) || shell.Name == loginShell {
+			shells = append(shells, shell)
+		}
+	}
+	return shells
+}
+
+func posixShellSnippet(sdkman bool, nvm bool) string {
+	var b strings.Builder
+	if nvm {
+		b.WriteString(`export NVM_DIR="` + NVMDir() + `"` + "\n")
+		b.WriteString(`[ -s "$NVM_DIR/nvm.sh" ] && \. "$NVM_DIR/nvm.sh"` + "\n")
+		b.WriteString(`[ -s "$NVM_DIR/bash_completion" ] && \. "$NVM_DIR/bash_completion"` + "\n")
+	}
+	// SDKMan asks to be initialised last.
+	if sdkman {
+		b.WriteString(`export SDKMAN_DIR="` + SDKManDir() + `"` + "\n")
+		b.WriteString(`[ -s "$SDKMAN_DIR/bin/sdkman-init.sh" ] && source "$SDKMAN_DIR/bin/sdkman-init.sh"` + "\n")
+	}
+	return b.String()
+}
+
+// fishShellSnippet cannot source the bash init scripts, so it puts the
+// current SDKMan candidates and the default Node on PATH directly. SDKMan's
+// current symlinks follow `sdk default`; the Node path is rewritten whenever
+// the nvm default changes.
+func fishShellSnippet(sdkman bool, nvm bool) string {
+	var b strings.Builder
+	if sdkman {
+		b.WriteString(`set -gx SDKMAN_DIR "` + SDKManDir() + `"` + "\n")
+		b.WriteString("for candidate_bin in $SDKMAN_DIR/candidates/*/current/bin\n")
+		b.WriteString("    contains $candidate_bin $PATH; or set -gx PATH $candidate_bin $PATH\n")
+		b.WriteString("end\n")
+		b.WriteString(`test -d "$SDKMAN_DIR/candidates/java/current"; and set -gx JAVA_HOME "$SDKMAN_DIR/candidates/java/current"` + "\n")
+	}
+	if nvm {
+		b.WriteString(`set -gx NVM_DIR "` + NVMDir() + `"` + "\n")
+		if bin := NodeDefaultBin(); bin != "" {
+			b.WriteString(`if test -d "` + bin + `"; and not contains "` + bin + `" $PATH` + "\n")
+			b.WriteString(`    set -gx PATH "` + bin + `" $PATH` + "\n")
+			b.WriteString("end\n")
+		}
+	}
+	return b.String()
+}
+
+// nushellSnippet goes into env.nu, where PATH is already a list.
+func nushellSnippet(sdkman bool, nvm bool) string {
+	var b strings.Builder
+	if sdkman {
+		b.WriteString(`$env.SDKMAN_DIR = "` + SDKManDir() + `"` + "\n")
+		b.WriteString(`$env.PATH = ($env.PATH | prepend (glob $"($env.SDKMAN_DIR)/candidates/*/current/bin") | uniq)` + "\n")
+		b.WriteString(`if ($"($env.SDKMAN_DIR)/candidates/java/current" | path exists) { $env.JAVA_HOME = $"($env.SDKMAN_DIR)/candidates/java/current" }` + "\n")
+	}
+	if nvm {
+		b.WriteString(`$env.NVM_DIR = "` + NVMDir() + `"` + "\n")
+		if bin := NodeDefaultBin(); bin != "" {
+			b.WriteString(`if ("` + bin + `" | path exists) { $env.PATH = ($env.PATH | prepend "` + bin + `" | uniq) }` + "\n")
+		}
+	}
+	return b.String()
+}
+
+// PlanShellIntegration computes the managed block for every detected shell
+// without writing anything.
+func PlanShellIntegration() ([]ShellChange, error) {
+	cfg := CurrentConfig()
+	var changes []ShellChange
+	for _, shell := range DetectShells() {
+		before, err := readRCFile(shell.RCFile)
+		if err != nil {
+			return changes, err
+		}
+		outside := removeLegacyLines(stripManagedBlock(before))
+		sdkman := cfg.Providers.SDKMan && !strings.Contains(outside, "sdkman-init.sh") && !strings.Contains(outside, "SDKMAN_DIR")
+		nvm := cfg.Providers.NVM && !strings.Contains(outside, "nvm.sh") && !strings.Contains(outside, "NVM_DIR")
+		body := shell.snippet(sdkman, nvm)
+		after := outside
+		if body != "" {
+			after = appendManagedBlock(outside, body)
+		}
+		changes = append(changes, ShellChange{Shell: shell, Before: before, After: after})
+		previous, err := planPreviousRemoval(shell)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, previous...)
+	}
+	return changes, nil
+}
+
+func planPreviousRemoval(shell Shell) ([]ShellChange, error) {
+	var changes []ShellChange
+	for _, path := range shell.Previous {
+		before, err := readRCFile(path)
+		if err != nil {
+			return changes, err
+		}
+		old := Shell{Name: shell.Name, RCFile: path}
+		changes = append(changes, ShellChange{Shell: old, Before: before, After: stripManagedBlock(before), Removed: true})
+	}
+	return changes, nil
+}
+
+// PlanShellRemoval computes the rc files with the managed block taken out.
+func PlanShellRemoval() ([]ShellChange, error) {
+	var changes []ShellChange
+	for _, shell := range DetectShells() {
+		before, err := readRCFile(shell.RCFile)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, ShellChange{Shell: shell, Before: before, After: stripManagedBlock(before), Removed: true})
+		previous, err := planPreviousRemoval(shell)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, previous...)
+	}
+	return changes, nil
+}
+
+// ApplyShellChange backs the rc file up next to itself and then replaces it.
+// Files owned by the app and rewrites of just the managed block are not
+// backed up.
+func ApplyShellChange(change ShellChange) error {
+	if !change.Changed() {
+		return nil
+	}
+	path := change.Shell.RCFile
+	// Rewriting our own block, e.g. after the default Node changed, leaves
+	// the user's lines alone and would only pile up backups.
+	blockOnly := !change.Removed && change.BlockOnly()
+	if FileExists(path) && !change.Shell.Dedicated && !blockOnly {
+		backup := backupPath(path)
+		if err := os.WriteFile(backup, []byte(change.Before), 0644); err != nil {
+			return fmt.Errorf("backing up %s: %w", path, err)
+		}
+		slog.Info("backed up shell config", "path", path, "backup", backup)
+	}
+	if change.Shell.Dedicated && strings.TrimSpace(change.After) == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		slog.Info("removed shell config", "path", path)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp := path + ".sdk-ui-go.tmp"
+	if err := os.WriteFile(tmp, []byte(change.After), mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	slog.Info("updated shell config", "path", path, "removed", change.Removed)
+	return nil
+}
+
+// backupPath names a backup next to path, stamped so repeated backups never
+// overwrite each other.
+func backupPath(path string) string {
+	return fmt.Sprintf("%s.sdk-ui-go.%s.bak", path, time.Now().Format("20060102-150405"))
+}
+
+func readRCFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(data), err
+}
+
+func stripManagedBlock(content string) string {
+	start := strings.Index(content, shellBlockStart)
+	if start < 0 {
+		return content
+	}
+	end := strings.Index(content[start:], shellBlockEnd)
+	if end < 0 {
+		return content
+	}
+	end += start + len(shellBlockEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	before := strings.TrimRight(content[:start], "\n")
+	if before != "" {
+		before += "\n"
+	}
+	return before + content[end:]
+}
+
+// removeLegacyLines drops the legacy lines together with the blank line
+// earlier versions wrote before each of them.
+func removeLegacyLines(content string) string {
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		legacy := false
+		for _, l := range legacyShellLines {
+			if strings.TrimSpace(line) == l {
+				legacy = true
+			}
+		}
+		if !legacy {
+			kept = append(kept, line)
+		} else if len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+			kept = kept[:len(kept)-1]
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// PlanLegacyRemoval computes the rc files earlier versions wrote unmarked
+// lines to with those lines taken out.
+func PlanLegacyRemoval() ([]ShellChange, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	var changes []ShellChange
+	for _, name := range []string{".bashrc", ".zshrc", ".profile"} {
+		path := filepath.Join(homeDir, name)
+		before, err := readRCFile(path)
+		if err != nil {
+			return changes, err
+		}
+		if after := removeLegacyLines(before); after != before {
+			shell := Shell{Name: strings.TrimPrefix(name, "."), RCFile: path}
+			changes = append(changes, ShellChange{Shell: shell, Before: before, After: after, Removed: true})
+		}
+	}
+	return changes, nil
+}
+
+func appendManagedBlock(content string, body string) string {
+	content = strings.TrimRight(content, "\n")
+	if content != "" {
+		content += "\n\n"
+	}
+	return content + shellBlockStart + "\n" + shellBlockNote + "\n" + body + shellBlockEnd + "\n"
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(content, "\n"), "\n")
+}
+
+// lineDiff prints the lines only in a with "-" and only in b with "+", using
+// a longest common subsequence; rc files are small enough for O(n*m).
+func lineDiff(a []string, b []string) string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			out.WriteString("+ " + b[j] + "\n")
+			j++
+		default:
+			out.WriteString("- " + a[i] + "\n")
+			i++
+		}
+	}
+	return out.String()
+}
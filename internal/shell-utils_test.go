@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// useConfig makes cfg current for the test and restores the previous config
+// and tool directory variables afterwards.
+func useConfig(t *testing.T, cfg Config) {
+	t.Setenv("SDKMAN_DIR", "")
+	t.Setenv("NVM_DIR", "")
+	previous := CurrentConfig()
+	setConfig(cfg)
+	t.Cleanup(func() {
+		configMu.Lock()
+		config = previous
+		configMu.Unlock()
+	})
+}
+
+// useToolDirs points SDKMan and NVM at a temp home with node v20.11.1
+// installed as the default, and returns the home so output can be made
+// independent of it.
+func useToolDirs(t *testing.T) string {
+	home := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.SDKManDir = filepath.Join(home, ".sdkman")
+	cfg.NVMDir = filepath.Join(home, ".nvm")
+	useConfig(t, cfg)
+	if err := os.MkdirAll(filepath.Join(cfg.NVMDir, "versions", "node", "v20.11.1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(cfg.NVMDir, "alias"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.NVMDir, "alias", "default"), []byte("20\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+func checkGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "shell", name+".golden")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s, run with -update to create it: %v", path, err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs:\n%s", path, lineDiff(splitLines(string(want)), splitLines(got)))
+	}
+}
+
+func TestShellSnippets(t *testing.T) {
+	home := useToolDirs(t)
+	snippets := map[string]func(bool, bool) string{
+		"posix": posixShellSnippet,
+		"fish":  fishShellSnippet,
+		"nu":    nushellSnippet,
+	}
+	tools := []struct {
+		name   string
+		sdkman bool
+		nvm    bool
+	}{
+		{"both", true, true},
+		{"sdkman", true, false},
+		{"nvm", false, true},
+	}
+	for shell, snippet := range snippets {
+		for _, tools := range tools {
+			name := shell + "-" + tools.name
+			t.Run(name, func(t *testing.T) {
+				block := appendManagedBlock("", snippet(tools.sdkman, tools.nvm))
+				checkGolden(t, name, strings.ReplaceAll(block, home, "/home/user"))
+			})
+		}
+	}
+}
+
+func TestStripManagedBlock(t *testing.T) {
+	block := shellBlockStart + "\n" + shellBlockNote + "\nexport A=1\n" + shellBlockEnd + "\n"
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no block", "alias ll='ls -l'\n", "alias ll='ls -l'\n"},
+		{"empty", "", ""},
+		{"only block", block, ""},
+		{"block at the end", "alias ll='ls -l'\n\n" + block, "alias ll='ls -l'\n"},
+		{"block in the middle", "alias ll='ls -l'\n\n" + block + "export B=2\n", "alias ll='ls -l'\nexport B=2\n"},
+		{"block at the start", block + "export B=2\n", "export B=2\n"},
+		{"no trailing newline", "export B=2\n" + strings.TrimSuffix(block, "\n"), "export B=2\n"},
+		{"unterminated block", "export B=2\n" + shellBlockStart + "\nexport A=1\n", "export B=2\n" + shellBlockStart + "\nexport A=1\n"},
+	}
+	for _, tt := range tests {
+		if got := stripManagedBlock(tt.content); got != tt.want {
+			t.Errorf("%s: stripManagedBlock(%q) = %q, want %q", tt.name, tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestStripManagedBlockUndoesAppend(t *testing.T) {
+	content := "alias ll='ls -l'\n"
+	if got := stripManagedBlock(appendManagedBlock(content, "export A=1\n")); got != content {
+		t.Errorf("got %q, want %q", got, content)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    []string
+		b    []string
+		want string
+	}{
+		{"identical", []string{"a", "b"}, []string{"a", "b"}, ""},
+		{"both empty", nil, nil, ""},
+		{"new file", nil, []string{"a", "b"}, "+ a\n+ b\n"},
+		{"emptied", []string{"a", "b"}, nil, "- a\n- b\n"},
+		{"appended", []string{"a"}, []string{"a", "b", "c"}, "+ b\n+ c\n"},
+		{"removed in the middle", []string{"a", "b", "c"}, []string{"a", "c"}, "- b\n"},
+		{"replaced", []string{"a", "b", "c"}, []string{"a", "x", "c"}, "+ x\n- b\n"},
+	}
+	for _, tt := range tests {
+		if got := lineDiff(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: lineDiff(%q, %q) = %q, want %q", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestShellChangeBlockOnly(t *testing.T) {
+	block := appendManagedBlock("", "export A=1\n")
+	newBlock := appendManagedBlock("", "export A=2\n")
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   bool
+	}{
+		{"block rewritten", "alias ll='ls -l'\n\n" + block, "alias ll='ls -l'\n\n" + newBlock, true},
+		{"block removed", "alias ll='ls -l'\n\n" + block, "alias ll='ls -l'\n", true},
+		{"block added", "alias ll='ls -l'\n", "alias ll='ls -l'\n\n" + block, false},
+		{"new file", "", block, false},
+		{"user lines changed", "alias ll='ls -l'\n\n" + block, newBlock, false},
+	}
+	for _, tt := range tests {
+		change := ShellChange{Before: tt.before, After: tt.after}
+		if got := change.BlockOnly(); got != tt.want {
+			t.Errorf("%s: BlockOnly() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const DefaultStaleAfterDays = 90
+
+// VersionActivity is an installed version with the last time it was used.
+type VersionActivity struct {
+	VersionUsage
+	LastUsed time.Time
+}
+
+func (a VersionActivity) Age() time.Duration {
+	return time.Since(a.LastUsed)
+}
+
+// StaleVersions picks the versions in usages that were neither the default
+// nor run for longer than olderThan, oldest first. Last use is the latest of
+// the access times of the version's executables, the app's own history of
+// making it the default or upgrading it, and when it stopped being the
+// default, whether switched in the app or outside of it. Versions that were
+// never seen in use count from when they were installed.
+func StaleVersions(usages []VersionUsage, olderThan time.Duration) ([]VersionActivity, error) {
+	switched, err := lastSwitches()
+	if err != nil {
+		return nil, err
+	}
+	var stale []VersionActivity
+	for _, usage := range usages {
+		if usage.Use {
+			continue
+		}
+		lastUsed := binAccessTime(usage.Path)
+		if t := switched[usage.Tool+"@"+usage.Version]; t.After(lastUsed) {
+			lastUsed = t
+		}
+		if lastUsed.IsZero() {
+			if info, err := os.Stat(usage.Path); err == nil {
+				lastUsed = info.ModTime()
+			}
+		}
+		if time.Since(lastUsed) > olderThan {
+			stale = append(stale, VersionActivity{VersionUsage: usage, LastUsed: lastUsed})
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].LastUsed.Before(stale[j].LastUsed) })
+	return stale, nil
+}
+
+const upgradeDetailPrefix = "from "
+
+// UpgradeDetail is the history detail of upgrading from version, which
+// counts the old version as used until the upgrade.
+func UpgradeDetail(version string) string {
+	return upgradeDetailPrefix + version
+}
+
+// lastSwitches maps tool@version to when the app last made it the default
+// or upgraded away from it, or when it stopped being the default.
+func lastSwitches() (map[string]time.Time, error) {
+	entries, err := ReadHistory(HistoryFilter{})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].End.Before(entries[j].End) })
+	switched := make(map[string]time.Time)
+	use := func(key string, t time.Time) {
+		if t.After(switched[key]) {
+			switched[key] = t
+		}
+	}
+	defaults := make(map[string]string)
+	for _, entry := range entries {
+		if entry.ExitCode != 0 {
+			continue
+		}
+		switch entry.Action {
+		case "default":
+			if previous := defaults[entry.Tool]; previous != "" && previous != entry.Version {
+				use(entry.Tool+"@"+previous, entry.End)
+			}
+			defaults[entry.Tool] = entry.Version
+		case "install":
+		case "upgrade":
+			if previous := strings.TrimPrefix(entry.Detail, upgradeDetailPrefix); previous != entry.Detail {
+				use(entry.Tool+"@"+previous, entry.End)
+			}
+		default:
+			continue
+		}
+		use(entry.Tool+"@"+entry.Version, entry.End)
+	}
+	for key, t := range loadDefaultsState().Until {
+		use(key, t)
+	}
+	return switched, nil
+}
+
+// defaultsState remembers the default version of every tool and when earlier
+// defaults stopped being one, so versions switched away from in a shell
+// count as used until then.
+type defaultsState struct {
+	Checked time.Time            `json:"checked"`
+	Current map[string]string    `json:"current"`
+	Until   map[string]time.Time `json:"until"`
+}
+
+// RecordDefaults notes the tools whose default changed since the last call.
+// The change is dated by the `current` link or nvm's default alias when they
+// were touched after the last call, otherwise by now, e.g. when the alias
+// resolves to a newly installed patch.
+func RecordDefaults() error {
+	state := loadDefaultsState()
+	now := time.Now()
+	current := currentDefaults()
+	for tool, previous := range state.Current {
+		if previous == "" || current[tool].version == previous {
+			continue
+		}
+		until := now
+		if changed := current[tool].changed; changed.After(state.Checked) && changed.Before(now) {
+			until = changed
+		}
+		state.Until[tool+"@"+previous] = until
+	}
+	state.Checked = now
+	state.Current = make(map[string]string)
+	for tool, d := range current {
+		state.Current[tool] = d.version
+	}
+	return saveDefaultsState(state)
+}
+
+type toolDefault struct {
+	version string
+	changed time.Time
+}
+
+// currentDefaults reads the default version of every SDKMan candidate and of
+// Node, with when its link or alias last changed.
+func currentDefaults() map[string]toolDefault {
+	defaults := make(map[string]toolDefault)
+	names, _ := LocalCandidateNames()
+	for _, name := range names {
+		link := filepath.Join(SDKManCandidatesDir(), name, "current")
+		target, err := os.Readlink(link)
+		if err != nil {
+			continue
+		}
+		d := toolDefault{version: filepath.Base(target)}
+		if info, err := os.Lstat(link); err == nil {
+			d.changed = info.ModTime()
+		}
+		defaults[name] = d
+	}
+	if versions, err := LocalNodeVersions(); err == nil {
+		for _, v := range versions {
+			if !v.Use {
+				continue
+			}
+			d := toolDefault{version: v.Identifier}
+			if info, err := os.Stat(filepath.Join(NVMDir(), "alias", "default")); err == nil {
+				d.changed = info.ModTime()
+			}
+			defaults[NodeWatchKey] = d
+		}
+	}
+	return defaults
+}
+
+func defaultsStatePath() (string, error) {
+	dir, err := AppConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "defaults.json"), nil
+}
+
+func loadDefaultsState() defaultsState {
+	var state defaultsState
+	if path, err := defaultsStatePath(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			json.Unmarshal(data, &state)
+		}
+	}
+	if state.Until == nil {
+		state.Until = make(map[string]time.Time)
+	}
+	return state
+}
+
+func saveDefaultsState(state defaultsState) error {
+	path, err := defaultsStatePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// binAccessTime is the latest access time of the executables in dir/bin.
+func binAccessTime(dir string) time.Time {
+	var latest time.Time
+	entries, err := os.ReadDir(filepath.Join(dir, "bin"))
+	if err != nil {
+		return latest
+	}
+	for _, entry := range entries {
+		info, err := os.Stat(filepath.Join(dir, "bin", entry.Name()))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if t := accessTime(info); t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
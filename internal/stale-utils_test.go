@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStaleVersionsCountsSwitchingAway(t *testing.T) {
+	isolateUserDirs(t)
+	useToolDirs(t)
+	old := time.Now().AddDate(-1, 0, 0)
+	for _, version := range []string{"v18.19.0", "v20.11.1"} {
+		dir := LocalNodeHome(version)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(dir, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RecordDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(NVMDir(), "alias", "default"), []byte("18\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordDefaults(); err != nil {
+		t.Fatal(err)
+	}
+
+	usages := []VersionUsage{
+		{Tool: NodeWatchKey, Version: "v20.11.1", Path: LocalNodeHome("v20.11.1")},
+		{Tool: NodeWatchKey, Version: "v18.19.0", Path: LocalNodeHome("v18.19.0"), Use: true},
+	}
+	stale, err := StaleVersions(usages, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 0 {
+		t.Errorf("v20.11.1 was the default until now, got stale %v", stale)
+	}
+	stale, err = StaleVersions(usages[:1], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 1 || time.Since(stale[0].LastUsed) > time.Minute {
+		t.Errorf("v20.11.1 should count as used when the default moved on, got %v", stale)
+	}
+}
+
+func TestLastSwitchesFromHistory(t *testing.T) {
+	isolateUserDirs(t)
+	useToolDirs(t)
+	day := func(n int) time.Time { return time.Date(2026, 1, n, 12, 0, 0, 0, time.UTC) }
+	entries := []HistoryEntry{
+		{Action: "default", Tool: "java", Version: "17.0.9-tem", End: day(1)},
+		{Action: "default", Tool: "java", Version: "21.0.2-tem", End: day(5)},
+		{Action: "default", Tool: "java", Version: "11.0.22-tem", End: day(7), ExitCode: 1},
+		{Action: "upgrade", Tool: "maven", Version: "3.9.9", Detail: UpgradeDetail("3.9.6"), End: day(9)},
+		{Action: "uninstall", Tool: "gradle", Version: "8.5", End: day(10)},
+	}
+	for _, entry := range entries {
+		entry.Start = entry.End
+		if err := AppendHistory(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	switched, err := lastSwitches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Time{
+		"java@17.0.9-tem": day(5),
+		"java@21.0.2-tem": day(5),
+		"maven@3.9.9":     day(9),
+		"maven@3.9.6":     day(9),
+	}
+	for key, t0 := range want {
+		if !switched[key].Equal(t0) {
+			t.Errorf("%s: got %v, want %v", key, switched[key], t0)
+		}
+	}
+	for _, key := range []string{"java@11.0.22-tem", "gradle@8.5"} {
+		if !switched[key].IsZero() {
+			t.Errorf("%s should not count as used, got %v", key, switched[key])
+		}
+	}
+}
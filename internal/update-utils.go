@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"os"
+	"sort"
+)
+
+// VersionUpdate is a newer release in the same line (see VersionLine) as an
+// installed version, e.g. 21.0.3-tem for 21.0.2-tem.
+type VersionUpdate struct {
+	Tool    string
+	Current string
+	Latest  string
+	// Default is set when Current is the tool's default version.
+	Default bool
+}
+
+func (u VersionUpdate) String() string {
+	return u.Tool + " " + u.Current + " → " + u.Latest
+}
+
+// CheckUpdates compares the installed versions against the cached remote
+// lists the menus keep, so it never waits on the network; tools whose list
+// was not loaded yet are skipped.
+func CheckUpdates() ([]VersionUpdate, error) {
+	var updates []VersionUpdate
+	names, err := LocalCandidateNames()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, name := range names {
+		entry, ok := LoadCache(SDKManCacheKey(name))
+		if !ok {
+			continue
+		}
+		installed, err := LocalCandidateVersions(name)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, FindUpdates(name, entry.Candidates, installed)...)
+	}
+	if entry, ok := LoadCache(NodeCacheKey); ok {
+		installed, err := LocalNodeVersions()
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		updates = append(updates, FindUpdates(NodeWatchKey, entry.Candidates, installed)...)
+	}
+	return updates, nil
+}
+
+// FindUpdates reports, per line, the newest remote version when it is newer
+// than the newest installed one in that line and not installed itself.
+func FindUpdates(tool string, remote []Candidate, installed []Candidate) []VersionUpdate {
+	newestRemote := make(map[string]Candidate)
+	for _, c := range SortCandidates(append([]Candidate{}, remote...)) {
+		line := VersionLine(c.Identifier)
+		if _, ok := newestRemote[line]; !ok {
+			newestRemote[line] = c
+		}
+	}
+	isInstalled := make(map[string]bool)
+	for _, c := range installed {
+		isInstalled[c.Identifier] = true
+	}
+	newestInstalled := make(map[string]Candidate)
+	defaultLine := ""
+	for _, c := range SortCandidates(append([]Candidate{}, installed...)) {
+		if c.Custom {
+			continue
+		}
+		line := VersionLine(c.Identifier)
+		if _, ok := newestInstalled[line]; !ok {
+			newestInstalled[line] = c
+		}
+		if c.Use {
+			defaultLine = line
+		}
+	}
+
+	var updates []VersionUpdate
+	for line, current := range newestInstalled {
+		latest, ok := newestRemote[line]
+		if !ok || isInstalled[latest.Identifier] || !newerVersion(latest.Identifier, current.Identifier) {
+			continue
+		}
+		// When the default is an older patch than another installed
+		// version of its line, the upgrade still replaces the default.
+		update := VersionUpdate{Tool: tool, Current: current.Identifier, Latest: latest.Identifier}
+		for _, c := range installed {
+			if c.Use && line == defaultLine {
+				update.Current = c.Identifier
+				update.Default = true
+			}
+		}
+		updates = append(updates, update)
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].Current < updates[j].Current })
+	return updates
+}
+
+func newerVersion(a string, b string) bool {
+	return compareVersions(a, b) > 0
+}
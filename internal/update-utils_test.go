@@ -0,0 +1,42 @@
+package internal
+
+import "testing"
+
+func TestFindUpdatesKeepsQualifiedLinesApart(t *testing.T) {
+	remote := []Candidate{
+		{Identifier: "21.0.4.crac-zulu"},
+		{Identifier: "21.0.4.fx-zulu"},
+		{Identifier: "21.0.3-zulu"},
+		{Identifier: "21.0.2-zulu"},
+	}
+	installed := []Candidate{{Identifier: "21.0.2-zulu", Install: true, Use: true}}
+	updates := FindUpdates("java", remote, installed)
+	if len(updates) != 1 {
+		t.Fatalf("got %v, want one update", updates)
+	}
+	if got := updates[0]; got.Current != "21.0.2-zulu" || got.Latest != "21.0.3-zulu" || !got.Default {
+		t.Errorf("got %+v, want 21.0.2-zulu → 21.0.3-zulu as the default", got)
+	}
+}
+
+func TestFindUpdatesQualifiedLine(t *testing.T) {
+	remote := []Candidate{
+		{Identifier: "21.0.5.fx-zulu"},
+		{Identifier: "21.0.5-zulu"},
+		{Identifier: "21.0.4.fx-zulu"},
+	}
+	installed := []Candidate{{Identifier: "21.0.4.fx-zulu", Install: true}}
+	updates := FindUpdates("java", remote, installed)
+	if len(updates) != 1 || updates[0].Latest != "21.0.5.fx-zulu" {
+		t.Errorf("got %v, want 21.0.4.fx-zulu → 21.0.5.fx-zulu", updates)
+	}
+}
+
+func TestFindUpdatesTwoPartVersions(t *testing.T) {
+	remote := []Candidate{{Identifier: "8.10"}, {Identifier: "8.9"}, {Identifier: "8.8"}}
+	installed := []Candidate{{Identifier: "8.9", Install: true, Use: true}}
+	updates := FindUpdates("gradle", remote, installed)
+	if len(updates) != 1 || updates[0].Latest != "8.10" {
+		t.Errorf("got %v, want 8.9 → 8.10", updates)
+	}
+}
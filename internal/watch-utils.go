@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NodeWatchKey is the tool name WatchLocalInstalls reports for nvm changes;
+// every other name is an SDKMan candidate.
+const NodeWatchKey = "node"
+
+// WatchLocalInstalls polls the SDKMan candidates directory and nvm's Node
+// versions and calls onChange with the tool whose installed versions or
+// default changed. Polling keeps it dependency free and copes with the
+// directories not existing yet. Default changes are recorded for stale
+// version detection, including the ones made while the app was not
+// running. The returned func stops the watcher.
+func WatchLocalInstalls(interval time.Duration, onChange func(tool string)) func() {
+	stop := make(chan struct{})
+	go func() {
+		recordDefaults()
+		previous := localInstallSnapshot()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				current := localInstallSnapshot()
+				if !maps.Equal(previous, current) {
+					recordDefaults()
+				}
+				for tool, fingerprint := range current {
+					if previous[tool] != fingerprint {
+						onChange(tool)
+					}
+				}
+				for tool := range previous {
+					if _, ok := current[tool]; !ok {
+						onChange(tool)
+					}
+				}
+				previous = current
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
+
+func localInstallSnapshot() map[string]string {
+	snapshot := make(map[string]string)
+	if names, err := LocalCandidateNames(); err == nil {
+		for _, name := range names {
+			dir := filepath.Join(SDKManCandidatesDir(), name)
+			current, _ := os.Readlink(filepath.Join(dir, "current"))
+			snapshot[name] = dirFingerprint(dir) + "|" + current
+		}
+	}
+	alias, _ := os.ReadFile(filepath.Join(NVMDir(), "alias", "default"))
+	snapshot[NodeWatchKey] = dirFingerprint(NodeVersionsDir()) + "|" + strings.TrimSpace(string(alias))
+	return snapshot
+}
+
+func dirFingerprint(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func recordDefaults() {
+	if err := RecordDefaults(); err != nil {
+		slog.Error("recording default versions", "err", err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"github.com/getlantern/systray"
+	"github.com/ncruces/zenity"
+	"sdk-ui-go/internal"
+	"sync"
+)
+
+// nodeLines groups the Node version items under one sub-menu per major
+// line, e.g. "v20.x (Iron LTS)".
+type nodeLines struct {
+	mu        sync.Mutex
+	parent    *systray.MenuItem
+	items     map[int]*systray.MenuItem
+	codenames map[int]string
+}
+
+func newNodeLines(parent *systray.MenuItem) *nodeLines {
+	return &nodeLines{parent: parent, items: make(map[int]*systray.MenuItem), codenames: make(map[int]string)}
+}
+
+// item returns the sub-menu of v's line, adding it the first time the line
+// is seen. Lines are added in the order the sorted list brings them, newest
+// first.
+func (l *nodeLines) item(v internal.Candidate) *systray.MenuItem {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	major := internal.MajorVersion(v.Identifier)
+	if v.LTS != "" {
+		l.codenames[major] = v.LTS
+	}
+	item, ok := l.items[major]
+	if !ok {
+		item = l.parent.AddSubMenuItemCheckbox(l.title(major), "", false)
+		l.items[major] = item
+	}
+	return item
+}
+
+func (l *nodeLines) title(major int) string {
+	if codename := l.codenames[major]; codename != "" {
+		return fmt.Sprintf("v%d.x (%s LTS)", major, codename)
+	}
+	return fmt.Sprintf("v%d.x", major)
+}
+
+// update checks the line holding the default version and shows the lines
+// with versions in the list. With hideOthers the remaining lines are hidden,
+// e.g. when a filter leaves them empty.
+func (l *nodeLines) update(versions []internal.Candidate, hideOthers bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	present := make(map[int]bool)
+	used := make(map[int]bool)
+	for _, v := range versions {
+		major := internal.MajorVersion(v.Identifier)
+		present[major] = true
+		used[major] = used[major] || v.Use
+		if v.LTS != "" {
+			l.codenames[major] = v.LTS
+		}
+	}
+	for major, item := range l.items {
+		item.SetTitle(l.title(major))
+		if used[major] {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+		switch {
+		case present[major]:
+			item.Show()
+		case hideOthers:
+			item.Hide()
+		}
+	}
+}
+
+// latestNodeItems are the "Install Latest LTS" and "Install Latest Current"
+// shortcuts. They install nvm's lts/* or node alias and make the alias the
+// default, so the default keeps following it.
+type latestNodeItems struct {
+	mu          sync.Mutex
+	ltsItem     *systray.MenuItem
+	currentItem *systray.MenuItem
+	lts         internal.Candidate
+	current     internal.Candidate
+}
+
+func addLatestNodeItems(parent *systray.MenuItem) *latestNodeItems {
+	latest := &latestNodeItems{
+		ltsItem:     parent.AddSubMenuItemCheckbox("Install Latest LTS", "Install lts/* and make it the default", false),
+		currentItem: parent.AddSubMenuItemCheckbox("Install Latest Current", "Install the newest release and make it the default", false),
+	}
+	latest.ltsItem.Hide()
+	latest.currentItem.Hide()
+	go func() {
+		for {
+			select {
+			case <-latest.ltsItem.ClickedCh:
+				latest.mu.Lock()
+				v := latest.lts
+				latest.mu.Unlock()
+				installLatestNode("Latest LTS", internal.NodeLTSAlias, v)
+			case <-latest.currentItem.ClickedCh:
+				latest.mu.Lock()
+				v := latest.current
+				latest.mu.Unlock()
+				installLatestNode("Latest Current", internal.NodeCurrentAlias, v)
+			}
+		}
+	}()
+	return latest
+}
+
+// update picks the latest releases from the full, unfiltered version list.
+func (l *latestNodeItems) update(versions []internal.Candidate) {
+	lts, current := internal.LatestNodeVersions(versions)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lts.LTS == "" {
+		// Offline lists only hold installed versions and know no LTS, so
+		// they cannot tell what is latest.
+		return
+	}
+	l.lts, l.current = lts, current
+	l.render()
+}
+
+// refreshLocal updates whether the latest releases are installed and the
+// default after installs outside the app.
+func (l *latestNodeItems) refreshLocal(local []internal.Candidate) {
+	installed := make(map[string]internal.Candidate)
+	for _, v := range local {
+		installed[v.Identifier] = v
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, latest := range []*internal.Candidate{&l.lts, &l.current} {
+		v, ok := installed[latest.Identifier]
+		latest.Install, latest.Use = ok, ok && v.Use
+	}
+	l.render()
+}
+
+func (l *latestNodeItems) render() {
+	for _, latest := range []struct {
+		item  *systray.MenuItem
+		title string
+		v     internal.Candidate
+	}{{l.ltsItem, "Latest LTS", l.lts}, {l.currentItem, "Latest Current", l.current}} {
+		if latest.v.Identifier == "" {
+			latest.item.Hide()
+			continue
+		}
+		title := latest.title + ": " + versionTitle(latest.v)
+		if !latest.v.Use {
+			title = "Install " + title
+		}
+		latest.item.SetTitle(title)
+		if latest.v.Use {
+			latest.item.Check()
+		} else {
+			latest.item.Uncheck()
+		}
+		latest.item.Show()
+	}
+}
+
+func installLatestNode(title string, alias string, v internal.Candidate) {
+	if v.Identifier == "" {
+		return
+	}
+	if v.Use {
+		zenity.Info("node "+v.Identifier+" is already the default.", zenity.Title(title))
+		return
+	}
+	enqueue(internal.Operation{Provider: internal.ProviderNVM, Action: "install", Tool: internal.NodeWatchKey, Version: v.Identifier, Run: func() (string, error) {
+		internal.Notify(title, "Installing node "+v.Identifier+" as "+alias)
+		out, err := internal.InstallNodeAlias(alias, v.Identifier)
+		if err != nil {
+			return out, failed(title, err)
+		}
+		internal.Notify(title, "node "+v.Identifier+" is installed and the default follows "+alias)
+		internal.InvalidateCache(internal.NodeCacheKey)
+		reloadMenus(false)
+		return out, nil
+	}})
+}
@@ -1,78 +1,404 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/gen2brain/beeep"
 	"github.com/getlantern/systray"
 	"github.com/ncruces/zenity"
+	"log/slog"
+	"os"
 	"sdk-ui-go/internal"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	maxErrorDetails = 2000
+	menuLoadWorkers = 4
 )
 
 var (
-	sdkmanInitScript = "~/.sdkman/bin/sdkman-init.sh"
-	candidate        = make(map[string][]VersionMenu)
+	sdkmanInitScript = internal.SDKManInitScript()
+	configErr        error
+	candidate        = make(map[string][]*VersionMenu)
+	candidateMu      sync.Mutex
+	offline          atomic.Bool
+	menuSources      = make(map[string]*menuSource)
+	menuSourcesMu    sync.Mutex
+	stopWatcher      = func() {}
+	stopListener     = func() {}
+	queue            = internal.NewOperationQueue()
+)
+
+const (
+	nodeMenuKey   = "node[nvm]"
+	watchInterval = 5 * time.Second
 )
 
+// menuSource knows how to fill one version menu: load fetches the full list
+// (cache, remote or offline), refreshLocal only re-reads what is installed
+// and fetch gets the remote list that is cached under cacheKey.
+type menuSource struct {
+	cacheKey     string
+	fetch        func() ([]internal.Candidate, error)
+	load         func()
+	refreshLocal func()
+}
+
 type VersionMenu struct {
-	MenuItem *systray.MenuItem
-	Title    string
+	MenuItem      *systray.MenuItem
+	Title         string
+	Version       string
+	UninstallItem *systray.MenuItem
+	OpenHomeItem  *systray.MenuItem
+	// InstalledItems are further items that only apply to installed
+	// versions, e.g. a Node version's global packages.
+	InstalledItems []*systray.MenuItem
+	// RefreshInstalled, if set, updates InstalledItems while the version is
+	// installed.
+	RefreshInstalled func()
+	// Label is shown after the version, e.g. a Node LTS codename.
+	Label string
+}
+
+func (v *VersionMenu) title() string {
+	if v.Label != "" {
+		return v.Version + " (" + v.Label + ")"
+	}
+	return v.Version
+}
+
+func (v *VersionMenu) SetState(install bool, use bool) {
+	if install {
+		v.MenuItem.SetTitle(eolMarker(v) + v.title() + "[Installed]")
+		v.MenuItem.SetTooltip(versionTooltip(v))
+		v.UninstallItem.Show()
+		v.OpenHomeItem.Show()
+	} else {
+		v.MenuItem.SetTitle(v.title())
+		v.MenuItem.SetTooltip("")
+		v.UninstallItem.Hide()
+		v.OpenHomeItem.Hide()
+	}
+	for _, extra := range v.InstalledItems {
+		if install {
+			extra.Show()
+		} else {
+			extra.Hide()
+		}
+	}
+	if install && v.RefreshInstalled != nil {
+		v.RefreshInstalled()
+	}
+	if use {
+		v.MenuItem.Check()
+	} else {
+		v.MenuItem.Uncheck()
+	}
+}
+
+func versionTitle(v internal.Candidate) string {
+	title := v.Identifier
+	if v.LTS != "" {
+		title += " (" + v.LTS + ")"
+	}
+	if v.Install {
+		return title + "[Installed]"
+	}
+	return title
+}
+
+func addVersionMenu(key string, menu *VersionMenu) {
+	candidateMu.Lock()
+	defer candidateMu.Unlock()
+	candidate[key] = append(candidate[key], menu)
+}
+
+// markUsed checks used as the installed default and unchecks its siblings.
+func markUsed(key string, used *VersionMenu) {
+	candidateMu.Lock()
+	defer candidateMu.Unlock()
+	for _, v := range candidate[key] {
+		if v != used {
+			v.MenuItem.Uncheck()
+		}
+	}
+	used.SetState(true, true)
+}
+
+// overlayLocalState applies the locally installed versions to the items for
+// key without touching the rest of the list.
+func overlayLocalState(key string, local []internal.Candidate, newItem func(internal.Candidate) *VersionMenu) {
+	candidateMu.Lock()
+	defer candidateMu.Unlock()
+	installed := make(map[string]internal.Candidate)
+	for _, v := range local {
+		installed[v.Identifier] = v
+	}
+	seen := make(map[string]bool)
+	for _, menu := range candidate[key] {
+		v, ok := installed[menu.Version]
+		menu.SetState(ok, ok && v.Use)
+		seen[menu.Version] = true
+	}
+	for _, v := range internal.SortCandidates(local) {
+		if !seen[v.Identifier] {
+			menu := newItem(v)
+			menu.SetState(true, v.Use)
+			candidate[key] = append(candidate[key], menu)
+		}
+	}
+}
+
+// syncVersionMenu updates the existing items for key in place, appends items
+// for versions it has not seen yet and hides the ones that disappeared.
+func syncVersionMenu(key string, versions []internal.Candidate, newItem func(internal.Candidate) *VersionMenu) {
+	candidateMu.Lock()
+	defer candidateMu.Unlock()
+	existing := make(map[string]*VersionMenu)
+	for _, v := range candidate[key] {
+		existing[v.Version] = v
+	}
+	seen := make(map[string]bool)
+	for _, v := range versions {
+		seen[v.Identifier] = true
+		if menu, ok := existing[v.Identifier]; ok {
+			menu.SetState(v.Install, v.Use)
+			menu.MenuItem.Show()
+			continue
+		}
+		menu := newItem(v)
+		if v.Install {
+			menu.SetState(true, v.Use)
+		}
+		candidate[key] = append(candidate[key], menu)
+	}
+	for version, menu := range existing {
+		if !seen[version] {
+			menu.MenuItem.Hide()
+		}
+	}
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "history":
+			os.Exit(historyCommand(os.Args[2:]))
+		case "hook":
+			os.Exit(hookCommand(os.Args[2:]))
+		case "env":
+			os.Exit(envCommand())
+		}
+	}
+	verbose := flag.Bool("verbose", false, "log debug messages and mirror the log to stderr")
+	flag.Parse()
+	if err := internal.InitLogger(*verbose); err != nil {
+		slog.Error("initializing log file", "err", err)
+	}
+	if _, err := internal.LoadConfig(); err != nil {
+		slog.Error("loading config, using defaults", "err", err)
+		configErr = err
+	}
+	sdkmanInitScript = internal.SDKManInitScript()
 	systray.Run(OnReady, onExit)
 }
 
 func OnReady() {
+	startedAt := time.Now()
 	systray.SetIcon(internal.Icon)
 	systray.SetTitle("SDK")
 	systray.SetTooltip("SDK UI")
-	internal.InstallSDKMan()
-	internal.InstallNVM()
-	candidate := internal.CandidateList(sdkmanInitScript)
-
-	var wg sync.WaitGroup
-	var candidateMenuItemMap = make(map[string]*systray.MenuItem)
-	for _, c := range candidate {
-		item := systray.AddMenuItem(c, "")
-		candidateMenuItemMap[c] = item
+	if configErr != nil {
+		go showError("Settings", configErr)
 	}
+	cfg := internal.CurrentConfig()
+	// The online check dials out for up to three seconds, so it runs while
+	// the tray is drawn and the loads wait for it instead.
+	online := make(chan bool, 1)
+	go func() { online <- internal.IsOnline() }()
 
-	for _, c := range candidate {
-		wg.Add(1)
-		go func(c string) {
-			defer wg.Done()
-			item := candidateMenuItemMap[c]
-			addSubMenu(item, c)
-		}(c)
+	var loads []func()
+	var pendingCandidates *systray.MenuItem
+	cachedCandidates, cached := internal.PeekCachedNames(internal.SDKManCandidatesCacheKey)
+	if cfg.Providers.SDKMan && !cached {
+		// Without a cached list `sdk list` runs once the tray is drawn;
+		// until the next start the candidates go under one item.
+		pendingCandidates = systray.AddMenuItem("All candidates", "")
+		systray.AddSeparator()
+	}
+	if cfg.Providers.SDKMan && cached {
+		candidate := enabledCandidates(cfg, cachedCandidates)
+		candidateLoads := make(map[string]func())
+		favorites, others := splitFavorites(candidate, cfg)
+		for _, c := range favorites {
+			item := systray.AddMenuItem(c, "")
+			candidateLoads[c] = addSubMenu(item, c)
+		}
+		if len(favorites) > 0 && len(others) > 0 {
+			allItem := systray.AddMenuItem("All candidates", "")
+			for _, c := range others {
+				item := allItem.AddSubMenuItem(c, "")
+				candidateLoads[c] = addSubMenu(item, c)
+			}
+		} else {
+			for _, c := range others {
+				item := systray.AddMenuItem(c, "")
+				candidateLoads[c] = addSubMenu(item, c)
+			}
+		}
+		for _, c := range prioritizeCandidates(candidate) {
+			loads = append(loads, candidateLoads[c])
+		}
+		systray.AddSeparator()
 	}
-	wg.Wait()
-	systray.AddSeparator()
 
-	nvmSubMenu()
+	if cfg.Providers.NVM {
+		loadNode := nvmSubMenu()
+		loads = append([]func(){func() {
+			if !offline.Load() && cfg.AutoInstall {
+				bootstrapTool("NVM Installation", internal.InstallNVM)
+			}
+			loadNode()
+		}}, loads...)
+	}
 
 	systray.AddSeparator()
 	mSDKManVersion := systray.AddMenuItem("SDKMan Version", "")
-	sdkmanUpdateItem := systray.AddMenuItem("SDKMan Update", "")
+	sdkmanUpdateItem := systray.AddMenuItem("Update SDKMan Candidates", "Refresh SDKMan's candidate lists (sdk update)")
+	sdkmanUpgradeItem := systray.AddMenuItem("Upgrade SDKMan…", "Install the latest SDKMan release (sdk selfupdate)")
 	systray.AddSeparator()
 	nvmVersionItem := systray.AddMenuItem("NVM Version", "")
+	nvmUpgradeItem := systray.AddMenuItem("Upgrade NVM…", "Install the latest NVM release")
+	if !cfg.Providers.SDKMan {
+		mSDKManVersion.Hide()
+		sdkmanUpdateItem.Hide()
+		sdkmanUpgradeItem.Hide()
+	}
+	if !cfg.Providers.NVM {
+		nvmVersionItem.Hide()
+		nvmUpgradeItem.Hide()
+	}
+	systray.AddSeparator()
+	refreshItem := systray.AddMenuItem("Refresh", "Reload all version lists")
+	offlineItem := systray.AddMenuItemCheckbox("Offline Mode", "Only show installed versions and never touch the network", offline.Load())
+	queueItem := systray.AddMenuItem("Queue: idle", "")
+	queueItem.Disable()
+	storageItem := systray.AddMenuItem("Storage…", "See how much space installed versions use")
+	addCleanupMenu()
+	staleMenu = newStaleMenu(systray.AddMenuItem("Stale Versions", ""))
+	updatesMenu = newUpdatesMenu(systray.AddMenuItem("Updates Available", ""))
+	historyItem := systray.AddMenuItem("History", "")
+	historyMenu := newHistoryMenu(historyItem)
+	openLogItem := systray.AddMenuItem("Open Log", "")
+	settingsItem := systray.AddMenuItem("Edit Settings", "Changes apply after restarting the app")
+	addShellIntegrationMenu()
+	resetItem := systray.AddMenuItem("Reset / Uninstall…", "Remove what SDK UI added to your system")
+	queue.OnChange = func(state internal.QueueState) {
+		updateQueueItem(queueItem, state)
+		historyMenu.refresh()
+	}
+	queue.OnDone = showOperationError
 	systray.AddSeparator()
 	mQuit := systray.AddMenuItem("Quit", "Quit the whole app")
+	slog.Info("menu ready", "elapsed", time.Since(startedAt))
+	go func() {
+		if !<-online {
+			offline.Store(true)
+			offlineItem.Check()
+		}
+		if cfg.Providers.SDKMan {
+			if !offline.Load() && cfg.AutoInstall {
+				bootstrapTool("SDKMan Installation", internal.InstallSDKMan)
+			}
+			loads = append(loads, addPendingCandidates(cfg, pendingCandidates)...)
+		}
+		runLoads(loads, startedAt)
+		// The first update check does the same right away.
+		if offline.Load() || updateCheckInterval() <= 0 {
+			updatesMenu.refresh()
+			scanDiskUsage()
+		}
+		go every("retention", retentionScheduleInterval, applyScheduledRetention)
+		go every("eol check", func() time.Duration { return eolCheckInterval }, checkEOL)
+		every("update check", updateCheckInterval, func() { checkForUpdates(sdkmanUpgradeItem, nvmUpgradeItem) })
+	}()
+	go checkShellIntegration(false)
+	stopWatcher = internal.WatchLocalInstalls(watchInterval, refreshLocalState)
+	if stop, err := internal.ListenInstallRequests(promptProjectInstall); err != nil {
+		slog.Error("listening for install requests", "err", err)
+	} else {
+		stopListener = stop
+	}
 
 	go func() {
 		for {
 			select {
 			case <-mQuit.ClickedCh:
 				systray.Quit()
+			case <-settingsItem.ClickedCh:
+				path, err := internal.ConfigFilePath()
+				if err == nil {
+					err = internal.OpenInEditor(path)
+				}
+				if err != nil {
+					showError("Edit Settings", err)
+				}
+			case <-storageItem.ClickedCh:
+				go showStorage()
+			case <-resetItem.ClickedCh:
+				go resetApp()
+			case <-refreshItem.ClickedCh:
+				reloadMenus(true)
+			case <-offlineItem.ClickedCh:
+				if offlineItem.Checked() {
+					offlineItem.Uncheck()
+				} else {
+					offlineItem.Check()
+				}
+				offline.Store(offlineItem.Checked())
+				slog.Info("offline mode toggled", "offline", offline.Load())
+				reloadMenus(false)
+			case <-openLogItem.ClickedCh:
+				path, err := internal.LogFilePath()
+				if err == nil {
+					err = internal.OpenPath(path)
+				}
+				if err != nil {
+					showError("Open Log", err)
+				}
 			case <-sdkmanUpdateItem.ClickedCh:
-				beeep.Notify("SDKMan Update", "SDKMan is updating", "")
-				internal.SDKManUpdate(sdkmanInitScript)
-				beeep.Notify("SDKMan Update", "SDKMan has updated", "")
+				enqueue(internal.Operation{Provider: internal.ProviderSDKMan, Action: "update", Tool: "sdkman", Run: func() (string, error) {
+					internal.Notify("SDKMan Update", "Updating the SDKMan candidate lists")
+					out, err := internal.SDKManUpdate(sdkmanInitScript)
+					if err != nil {
+						return out, failed("SDKMan Update", err)
+					}
+					internal.Notify("SDKMan Update", "The SDKMan candidate lists have updated")
+					reloadMenus(true)
+					return out, nil
+				}})
+			case <-sdkmanUpgradeItem.ClickedCh:
+				go upgradeSDKMan(sdkmanUpgradeItem)
+			case <-nvmUpgradeItem.ClickedCh:
+				go upgradeNVM(nvmUpgradeItem)
 			case <-mSDKManVersion.ClickedCh:
-				zenity.Info(internal.SDKManVersion(sdkmanInitScript), zenity.Title("SDKMan Version"))
+				version, err := internal.SDKManVersion(sdkmanInitScript)
+				if err != nil {
+					showError("SDKMan Version", err)
+					continue
+				}
+				zenity.Info(version, zenity.Title("SDKMan Version"))
 			case <-nvmVersionItem.ClickedCh:
-				zenity.Info(internal.NVMVersion(), zenity.Title("NVM Version"))
+				version, err := internal.NVMVersion()
+				if err != nil {
+					showError("NVM Version", err)
+					continue
+				}
+				zenity.Info(version, zenity.Title("NVM Version"))
 			}
 
 		}
@@ -80,49 +406,351 @@ func OnReady() {
 
 }
 
-func addSubMenu(item *systray.MenuItem, title string) {
-	var versions []internal.Candidate
-	var versionMenu []VersionMenu
-	if strings.EqualFold(title, "Java") {
-		versions = internal.JavaVersionList(sdkmanInitScript)
+// addPendingCandidates fills parent with the candidates once the list is
+// fetched, for starts without a cached list, and returns their loads. With
+// a cached list parent is nil and only an expired cache is refreshed.
+func addPendingCandidates(cfg internal.Config, parent *systray.MenuItem) []func() {
+	if parent == nil {
+		if !offline.Load() {
+			sdkmanCandidates(cfg)
+		}
+		return nil
+	}
+	loading := parent.AddSubMenuItem("Loading…", "")
+	loading.Disable()
+	names := sdkmanCandidates(cfg)
+	if len(names) == 0 {
+		loading.SetTitle("No candidates found")
+		return nil
+	}
+	loading.Hide()
+	candidateLoads := make(map[string]func())
+	for _, c := range names {
+		candidateLoads[c] = addSubMenu(parent.AddSubMenuItem(c, ""), c)
+	}
+	var loads []func()
+	for _, c := range prioritizeCandidates(names) {
+		loads = append(loads, candidateLoads[c])
+	}
+	return loads
+}
+
+// sdkmanCandidates lists the candidates to show, limited to the ones enabled
+// in the config.
+func sdkmanCandidates(cfg internal.Config) []string {
+	var names []string
+	var err error
+	if offline.Load() {
+		names, err = internal.LocalCandidateNames()
 	} else {
-		versions = internal.OtherVersionList(title, sdkmanInitScript)
+		names, err = internal.CachedNames(internal.SDKManCandidatesCacheKey, internal.DefaultCacheTTL, func() ([]string, error) {
+			return internal.CandidateList(sdkmanInitScript)
+		})
+	}
+	if err != nil {
+		showError("SDKMan Candidates", err)
+	}
+	return enabledCandidates(cfg, names)
+}
+
+func enabledCandidates(cfg internal.Config, names []string) []string {
+	var enabled []string
+	for _, name := range names {
+		if cfg.CandidateEnabled(name) {
+			enabled = append(enabled, name)
+		}
+	}
+	return enabled
+}
+
+// splitFavorites returns the favorite candidates in the order they were
+// starred, followed by the remaining ones. Without favorites every candidate
+// stays in the root menu.
+func splitFavorites(names []string, cfg internal.Config) ([]string, []string) {
+	available := make(map[string]bool)
+	for _, name := range names {
+		available[name] = true
+	}
+	var favorites []string
+	for _, name := range cfg.Favorites {
+		if available[name] {
+			favorites = append(favorites, name)
+		}
+	}
+	var others []string
+	for _, name := range names {
+		if !cfg.IsFavorite(name) {
+			others = append(others, name)
+		}
+	}
+	return favorites, others
+}
+
+// addFavoriteItem lets the user star title. The root menu cannot be reordered
+// in place, so the new layout is used from the next start.
+func addFavoriteItem(item *systray.MenuItem, title string) {
+	favoriteItem := item.AddSubMenuItemCheckbox("★ Favorite", "Favorites are listed at the top of the menu after a restart", internal.CurrentConfig().IsFavorite(title))
+	go func() {
+		for range favoriteItem.ClickedCh {
+			favorite := !favoriteItem.Checked()
+			if err := internal.UpdateConfig(func(cfg *internal.Config) { cfg.SetFavorite(title, favorite) }); err != nil {
+				showError("Favorites", err)
+				continue
+			}
+			if favorite {
+				favoriteItem.Check()
+			} else {
+				favoriteItem.Uncheck()
+			}
+			internal.Notify("Favorites", "The menu will be reordered after restarting the app")
+		}
+	}()
+}
+
+// addFilterItem adds the per candidate version filter; reload re-applies it.
+func addFilterItem(item *systray.MenuItem, title string, filters []string, reload func()) {
+	labels := map[string]string{
+		internal.FilterAll:       "All versions",
+		internal.FilterInstalled: "Installed only",
+		internal.FilterLTS:       "LTS only",
+	}
+	filterItem := item.AddSubMenuItem("Filter", "")
+	current := internal.CurrentConfig().Filter(title)
+	options := make(map[string]*systray.MenuItem)
+	for _, filter := range filters {
+		options[filter] = filterItem.AddSubMenuItemCheckbox(labels[filter], "", filter == current)
+	}
+	for filter, option := range options {
+		go func(filter string, option *systray.MenuItem) {
+			for range option.ClickedCh {
+				if err := internal.UpdateConfig(func(cfg *internal.Config) { cfg.SetFilter(title, filter) }); err != nil {
+					showError("Filter", err)
+					continue
+				}
+				for f, o := range options {
+					if f == filter {
+						o.Check()
+					} else {
+						o.Uncheck()
+					}
+				}
+				reload()
+			}
+		}(filter, option)
 	}
-	versions = internal.SortCandidates(versions)
+}
+
+// addSubMenu builds the static part of a candidate menu and returns the
+// function that fills in its versions.
+func addSubMenu(item *systray.MenuItem, title string) func() {
 	addCustomItem := item.AddSubMenuItem("+ local "+title, "")
 	go func() {
 		for {
 			select {
 			case <-addCustomItem.ClickedCh:
-				id := internal.AddCustomCandidate(title, sdkmanInitScript)
+				id, err := internal.AddCustomCandidate(title, sdkmanInitScript)
+				if err != nil {
+					showError("Install failed", err)
+					continue
+				}
 				if id != "" {
-					customItem := item.AddSubMenuItem(id+"[Installed]", "")
-					candidate[title] = append(candidate[title], VersionMenu{MenuItem: customItem, Title: title})
-					addVersionItem(customItem, title, id, true)
+					customItem := item.AddSubMenuItemCheckbox(id+"[Installed]", "", false)
+					addVersionMenu(title, addVersionItem(customItem, title, id, true))
+					internal.InvalidateCache(internal.SDKManCacheKey(title))
 				}
 			}
 		}
 	}()
 
-	for _, v := range versions {
-		subItem := v.Identifier
-		if v.Install {
-			subItem = subItem + "[Installed]"
-		} else {
-			subItem = subItem + ""
+	addFavoriteItem(item, title)
+	filters := []string{internal.FilterAll, internal.FilterInstalled}
+	if strings.EqualFold(title, "Java") {
+		filters = append(filters, internal.FilterLTS)
+	}
+	var load func()
+	addFilterItem(item, title, filters, func() { go load() })
+	loaded := loadingPlaceholder(item)
+	newItem := func(v internal.Candidate) *VersionMenu {
+		versionItem := item.AddSubMenuItemCheckbox(versionTitle(v), "", v.Use)
+		return addVersionItem(versionItem, title, v.Identifier, v.Install)
+	}
+	fetch := func() ([]internal.Candidate, error) {
+		if strings.EqualFold(title, "Java") {
+			return internal.JavaVersionList(sdkmanInitScript)
+		}
+		return internal.OtherVersionList(title, sdkmanInitScript)
+	}
+	load = func() {
+		loadVersions(item, internal.SDKManCacheKey(title), fetch, func() ([]internal.Candidate, error) {
+			return internal.LocalCandidateVersions(title)
+		}, func(versions []internal.Candidate) {
+			versions = internal.FilterCandidates(title, versions, internal.CurrentConfig().Filter(title))
+			syncVersionMenu(title, versions, newItem)
+			loaded(versions)
+		})
+	}
+	registerMenuSource(title, &menuSource{cacheKey: internal.SDKManCacheKey(title), fetch: fetch, load: load, refreshLocal: func() {
+		versions, err := internal.LocalCandidateVersions(title)
+		if err != nil && !os.IsNotExist(err) {
+			slog.Warn("reading local versions", "candidate", title, "err", err)
+			return
+		}
+		versions = internal.FilterCandidates(title, versions, internal.CurrentConfig().Filter(title))
+		overlayLocalState(title, versions, newItem)
+		if len(versions) > 0 {
+			loaded(versions)
 		}
+	}})
+	return load
+}
 
-		versionItem := item.AddSubMenuItemCheckbox(subItem, "", v.Use)
-		versionMenu = append(versionMenu, VersionMenu{MenuItem: versionItem, Title: title})
-		addVersionItem(versionItem, title, v.Identifier, v.Install)
+// loadingPlaceholder shows "Loading…" under parent until the first version
+// list has been applied.
+func loadingPlaceholder(parent *systray.MenuItem) func([]internal.Candidate) {
+	loading := parent.AddSubMenuItem("Loading…", "")
+	loading.Disable()
+	return func(versions []internal.Candidate) {
+		if len(versions) == 0 {
+			loading.SetTitle("No versions found")
+			loading.Show()
+			return
+		}
+		loading.Hide()
+	}
+}
+
+// prioritizeCandidates moves the candidates with something installed to the
+// front so their versions are loaded first.
+func prioritizeCandidates(names []string) []string {
+	local, _ := internal.LocalCandidateNames()
+	installed := make(map[string]bool)
+	for _, name := range local {
+		installed[name] = true
+	}
+	ordered := make([]string, 0, len(names))
+	for _, name := range names {
+		if installed[name] {
+			ordered = append(ordered, name)
+		}
 	}
-	candidate[title] = versionMenu
+	for _, name := range names {
+		if !installed[name] {
+			ordered = append(ordered, name)
+		}
+	}
+	return ordered
 }
 
-func addVersionItem(item *systray.MenuItem, title string, version string, install bool) {
+// runLoads fills in the version menus with at most menuLoadWorkers loads in
+// flight, so the tray is usable while dozens of `sdk list` calls run.
+func runLoads(loads []func(), startedAt time.Time) {
+	slots := make(chan struct{}, menuLoadWorkers)
+	var wg sync.WaitGroup
+	for _, load := range loads {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(load func()) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			load()
+		}(load)
+	}
+	wg.Wait()
+	slog.Info("all versions loaded", "elapsed", time.Since(startedAt))
+}
+
+func registerMenuSource(key string, source *menuSource) {
+	menuSourcesMu.Lock()
+	defer menuSourcesMu.Unlock()
+	menuSources[key] = source
+}
+
+func allMenuSources() []*menuSource {
+	menuSourcesMu.Lock()
+	defer menuSourcesMu.Unlock()
+	sources := make([]*menuSource, 0, len(menuSources))
+	for _, source := range menuSources {
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// reloadMenus re-reads every version menu, e.g. after switching offline mode.
+// With force the cached remote lists are refreshed as well.
+func reloadMenus(force bool) {
+	for _, source := range allMenuSources() {
+		if force {
+			internal.InvalidateCache(source.cacheKey)
+		}
+		go source.load()
+	}
+}
+
+// refreshLocalState is called by the filesystem watcher when versions were
+// installed, removed or switched outside the app.
+func refreshLocalState(tool string) {
+	key := tool
+	if tool == internal.NodeWatchKey {
+		key = nodeMenuKey
+	}
+	menuSourcesMu.Lock()
+	source, ok := menuSources[key]
+	menuSourcesMu.Unlock()
+	if !ok {
+		slog.Debug("ignoring change for tool without menu", "tool", tool)
+		return
+	}
+	slog.Info("local installs changed, refreshing menu", "tool", tool)
+	source.refreshLocal()
+	go scanDiskUsage()
+	go updatesMenu.refresh()
+	if tool == internal.NodeWatchKey {
+		refreshShellIntegration()
+	}
+}
+
+// loadVersions renders the cached version list right away and refreshes it in
+// the background once it has expired. Without a cache it fetches first. In
+// offline mode only the locally installed versions are listed.
+func loadVersions(item *systray.MenuItem, key string, fetch func() ([]internal.Candidate, error), local func() ([]internal.Candidate, error), apply func([]internal.Candidate)) {
+	if offline.Load() {
+		versions, err := local()
+		if err != nil {
+			item.SetTooltip(internal.ErrorSummary(err))
+		}
+		apply(internal.SortCandidates(versions))
+		return
+	}
+	entry, cached := internal.LoadCache(key)
+	if cached {
+		apply(internal.SortCandidates(entry.Candidates))
+		if !entry.Expired(internal.DefaultCacheTTL) {
+			return
+		}
+	}
+	refresh := func() {
+		fresh, changed, err := internal.RefreshCandidateCache(key, entry, fetch)
+		if err != nil {
+			item.SetTooltip(internal.ErrorSummary(err))
+			return
+		}
+		item.SetTooltip("")
+		if changed || !cached {
+			apply(internal.SortCandidates(fresh.Candidates))
+		}
+	}
+	if cached {
+		go refresh()
+	} else {
+		refresh()
+	}
+}
+
+func addVersionItem(item *systray.MenuItem, title string, version string, install bool) *VersionMenu {
 	installItem := item.AddSubMenuItem("Install && Use", "")
 	uninstallItem := item.AddSubMenuItem("Uninstall", "")
 	openHomeItem := item.AddSubMenuItem("Open Home", "")
+	menu := &VersionMenu{MenuItem: item, Title: title, Version: version, UninstallItem: uninstallItem, OpenHomeItem: openHomeItem}
 	if install == false {
 		uninstallItem.Hide()
 		openHomeItem.Hide()
@@ -131,109 +759,247 @@ func addVersionItem(item *systray.MenuItem, title string, version string, instal
 		for {
 			select {
 			case <-installItem.ClickedCh:
-				beeep.Notify("Install", "Verify Installation of "+title+" "+version, "")
-				internal.UseCandidate(title, version, sdkmanInitScript)
-				beeep.Notify("Install", title+" "+version+" has installed and Using", "")
-				for _, v := range candidate[title] {
-					if v.MenuItem != item {
-						v.MenuItem.Uncheck()
-					} else {
-						item.Check()
-						item.SetTitle(version + "[Installed]")
-					}
+				if offline.Load() {
+					enqueue(internal.Operation{Provider: internal.ProviderSDKMan, Action: "default", Tool: title, Version: version, Run: func() (string, error) {
+						out, err := internal.UseLocalCandidate(title, version)
+						if err != nil {
+							return out, failed("Use failed", err)
+						}
+						markUsed(title, menu)
+						return out, nil
+					}})
+					continue
 				}
-				openHomeItem.Show()
-				uninstallItem.Show()
-				installItem.Show()
+				enqueue(internal.Operation{Provider: internal.ProviderSDKMan, Action: "install", Tool: title, Version: version, Run: func() (string, error) {
+					internal.Notify("Install", "Verify Installation of "+title+" "+version)
+					out, err := internal.UseCandidate(title, version, sdkmanInitScript)
+					if err != nil {
+						return out, failed("Install failed", err)
+					}
+					internal.Notify("Install", title+" "+version+" has installed and Using")
+					markUsed(title, menu)
+					internal.InvalidateCache(internal.SDKManCacheKey(title))
+					return out, err
+				}})
 
 			case <-uninstallItem.ClickedCh:
+				// The item is reused across reloads, so the loop has to keep
+				// serving it.
 				if item.Checked() {
-					return
+					go zenity.Info(title+" "+version+" is the default version and cannot be uninstalled. Make another version the default first.",
+						zenity.Title("Uninstall"))
+					continue
 				}
-				beeep.Notify("Uninstall", "Uninstalling "+title+" "+version, "")
-				internal.UninstallCandidate(title, version, sdkmanInitScript)
-				beeep.Notify("Uninstall", title+" "+version+" has removed", "")
-				item.SetTitle(version)
-				uninstallItem.Hide()
-				openHomeItem.Hide()
-				installItem.Show()
+				enqueue(internal.Operation{Provider: internal.ProviderSDKMan, Action: "uninstall", Tool: title, Version: version, Run: func() (string, error) {
+					internal.Notify("Uninstall", "Uninstalling "+title+" "+version)
+					out, err := internal.UninstallCandidate(title, version, sdkmanInitScript)
+					if err != nil {
+						return out, failed("Uninstall failed", err)
+					}
+					internal.Notify("Uninstall", title+" "+version+" has removed")
+					menu.SetState(false, false)
+					internal.InvalidateCache(internal.SDKManCacheKey(title))
+					return out, err
+				}})
 
 			case <-openHomeItem.ClickedCh:
-				internal.OpenCandidateFolder(title, version, sdkmanInitScript)
+				if err := internal.OpenCandidateFolder(title, version, sdkmanInitScript); err != nil {
+					showError("Open Home", err)
+				}
 
 			}
 
 		}
 	}()
+	return menu
 }
 
-func nvmSubMenu() {
+// nvmSubMenu lists Node versions grouped by major line, with shortcuts to
+// the latest LTS and current releases.
+func nvmSubMenu() func() {
 	nodeItem := systray.AddMenuItem("node", "")
-	nodeVersionList := internal.NodeVersionList()
-	var versionMenu []VersionMenu
-	nodeVersionList = internal.SortCandidates(nodeVersionList)
-	for _, v := range nodeVersionList {
-		subItem := v.Identifier
-		if v.Install {
-			subItem = subItem + "[Installed]"
-		} else {
-			subItem = subItem + ""
-		}
-		versionItem := nodeItem.AddSubMenuItemCheckbox(subItem, "", v.Use)
-		versionMenu = append(versionMenu, VersionMenu{MenuItem: versionItem, Title: "node[nvm]"})
-		AddNodeVersionItem(versionItem, "node[nvm]", v.Identifier, v.Install)
+	latest := addLatestNodeItems(nodeItem)
+	var load func()
+	addFilterItem(nodeItem, "node", []string{internal.FilterAll, internal.FilterInstalled, internal.FilterLTS}, func() { go load() })
+	loaded := loadingPlaceholder(nodeItem)
+	lines := newNodeLines(nodeItem)
+	newItem := func(v internal.Candidate) *VersionMenu {
+		versionItem := lines.item(v).AddSubMenuItemCheckbox(versionTitle(v), "", v.Use)
+		menu := AddNodeVersionItem(versionItem, nodeMenuKey, v.Identifier, v.Install)
+		menu.Label = v.LTS
+		return menu
 	}
-	candidate["node[nvm]"] = versionMenu
-
+	load = func() {
+		loadVersions(nodeItem, internal.NodeCacheKey, internal.NodeVersionList, internal.LocalNodeVersions, func(versions []internal.Candidate) {
+			latest.update(versions)
+			versions = internal.FilterCandidates(internal.NodeWatchKey, versions, internal.CurrentConfig().Filter("node"))
+			syncVersionMenu(nodeMenuKey, versions, newItem)
+			lines.update(versions, true)
+			loaded(versions)
+		})
+	}
+	registerMenuSource(nodeMenuKey, &menuSource{cacheKey: internal.NodeCacheKey, fetch: internal.NodeVersionList, load: load, refreshLocal: func() {
+		versions, err := internal.LocalNodeVersions()
+		if err != nil && !os.IsNotExist(err) {
+			slog.Warn("reading local node versions", "err", err)
+			return
+		}
+		overlayLocalState(nodeMenuKey, versions, newItem)
+		lines.update(versions, false)
+		latest.refreshLocal(versions)
+		if len(versions) > 0 {
+			loaded(versions)
+		}
+	}})
+	return load
 }
 
-func AddNodeVersionItem(item *systray.MenuItem, title string, version string, install bool) {
+func AddNodeVersionItem(item *systray.MenuItem, title string, version string, install bool) *VersionMenu {
 	installItem := item.AddSubMenuItem("Install && Use", "")
 	uninstallItem := item.AddSubMenuItem("Uninstall", "")
 	openHomeItem := item.AddSubMenuItem("Open Home", "")
+	packagesItem := item.AddSubMenuItem("Global Packages…", "List and copy this version's global npm packages")
+	managersItem := item.AddSubMenuItem("Package Managers", "npm, corepack, pnpm and yarn of this version")
+	menu := &VersionMenu{MenuItem: item, Title: title, Version: version, UninstallItem: uninstallItem, OpenHomeItem: openHomeItem,
+		InstalledItems: []*systray.MenuItem{packagesItem, managersItem}}
+	menu.RefreshInstalled = addPackageManagersMenu(managersItem, version)
 	if install == false {
 		uninstallItem.Hide()
 		openHomeItem.Hide()
+		packagesItem.Hide()
+		managersItem.Hide()
 	}
 	go func() {
 		for {
 			select {
+			case <-packagesItem.ClickedCh:
+				showGlobalPackages(version)
 			case <-installItem.ClickedCh:
-				beeep.Notify("Install", "Verify Installation of "+title+" "+version, "")
-				internal.InstallNode(version)
-				beeep.Notify("Install", title+" "+version+" has installed and Using", "")
-				for _, v := range candidate["node[nvm]"] {
-					if v.MenuItem != item {
-						v.MenuItem.Uncheck()
-					} else {
-						item.Check()
-						item.SetTitle(version + "[Installed]")
-					}
+				if offline.Load() {
+					enqueue(internal.Operation{Provider: internal.ProviderNVM, Action: "default", Tool: "node", Version: version, Run: func() (string, error) {
+						out, err := internal.UseLocalNode(version)
+						if err != nil {
+							return out, failed("Use failed", err)
+						}
+						markUsed(nodeMenuKey, menu)
+						return out, nil
+					}})
+					continue
 				}
-				openHomeItem.Show()
-				uninstallItem.Show()
-				installItem.Show()
+				enqueue(internal.Operation{Provider: internal.ProviderNVM, Action: "install", Tool: "node", Version: version, Run: func() (string, error) {
+					internal.Notify("Install", "Verify Installation of "+title+" "+version)
+					out, err := internal.InstallNode(version)
+					if err != nil {
+						return out, failed("Install failed", err)
+					}
+					internal.Notify("Install", title+" "+version+" has installed and Using")
+					markUsed(nodeMenuKey, menu)
+					internal.InvalidateCache(internal.NodeCacheKey)
+					return out, err
+				}})
 
 			case <-uninstallItem.ClickedCh:
+				// The item is reused across reloads, so the loop has to keep
+				// serving it.
 				if item.Checked() {
-					return
+					go zenity.Info(title+" "+version+" is the default version and cannot be uninstalled. Make another version the default first.",
+						zenity.Title("Uninstall"))
+					continue
 				}
-				beeep.Notify("Uninstall", "Uninstalling "+title+" "+version, "")
-				internal.UninstallNode(version)
-				beeep.Notify("Uninstall", title+" "+version+" has removed", "")
-				item.SetTitle(version)
-				uninstallItem.Hide()
-				openHomeItem.Hide()
-				installItem.Show()
+				enqueue(internal.Operation{Provider: internal.ProviderNVM, Action: "uninstall", Tool: "node", Version: version, Run: func() (string, error) {
+					internal.Notify("Uninstall", "Uninstalling "+title+" "+version)
+					out, err := internal.UninstallNode(version)
+					if err != nil {
+						return out, failed("Uninstall failed", err)
+					}
+					internal.Notify("Uninstall", title+" "+version+" has removed")
+					menu.SetState(false, false)
+					internal.InvalidateCache(internal.NodeCacheKey)
+					return out, err
+				}})
 
 			case <-openHomeItem.ClickedCh:
-				internal.OpenNodeFolder(version)
+				if err := internal.OpenNodeFolder(version); err != nil {
+					showError("Open Home", err)
+				}
 			}
 		}
 	}()
+	return menu
+}
+
+// showError reports a failed operation with the captured command output; the
+// full details can be copied for bug reports.
+func showError(title string, err error) {
+	slog.Error("operation failed", "title", title, "err", err)
+	summary := internal.ErrorSummary(err)
+	details := internal.ErrorDetails(err)
+	shown := details
+	if len(shown) > maxErrorDetails {
+		shown = "…" + shown[len(shown)-maxErrorDetails:]
+	}
+	dialogErr := zenity.Error(summary+"\n\n"+shown, zenity.Title(title), zenity.ExtraButton("Copy details"))
+	if dialogErr == zenity.ErrExtraButton {
+		if err := internal.CopyToClipboard(details); err != nil {
+			slog.Error("copying details", "err", err)
+		}
+	}
+}
+
+// operationError is a failed operation's error with the title of the dialog
+// it is shown in.
+type operationError struct {
+	title string
+	err   error
+}
+
+func (e *operationError) Error() string { return e.err.Error() }
+func (e *operationError) Unwrap() error { return e.err }
+
+// failed is what an operation returns on failure. The dialog is only shown
+// once the operation has left the queue, so it never holds up the next one.
+func failed(title string, err error) error {
+	return &operationError{title: title, err: err}
+}
+
+func showOperationError(op internal.Operation, err error) {
+	if err == nil {
+		return
+	}
+	title := op.String() + " failed"
+	var opErr *operationError
+	if errors.As(err, &opErr) {
+		title = opErr.title
+	}
+	showError(title, err)
+}
+
+func enqueue(op internal.Operation) {
+	if !queue.Enqueue(op) {
+		internal.Notify("Queue", op.String()+" is already queued")
+	}
+}
+
+func updateQueueItem(item *systray.MenuItem, state internal.QueueState) {
+	if len(state.Running) == 0 && len(state.Pending) == 0 {
+		item.SetTitle("Queue: idle")
+		item.SetTooltip("")
+		return
+	}
+	item.SetTitle(fmt.Sprintf("Queue: %d running, %d pending", len(state.Running), len(state.Pending)))
+	var lines []string
+	for _, op := range state.Running {
+		lines = append(lines, "running: "+op.String())
+	}
+	for _, op := range state.Pending {
+		lines = append(lines, "pending: "+op.String())
+	}
+	item.SetTooltip(strings.Join(lines, "\n"))
 }
 
 func onExit() {
 	// clean up here
-	fmt.Println("Exiting...")
+	stopWatcher()
+	stopListener()
+	slog.Info("exiting")
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"github.com/getlantern/systray"
+	"github.com/ncruces/zenity"
+	"sdk-ui-go/internal"
+	"strings"
+)
+
+// showRelease puts the latest release into the upgrade item's title when the
+// installed manager is behind.
+func showRelease(item *systray.MenuItem, release internal.ToolRelease) {
+	if release.Outdated() {
+		item.SetTitle("Upgrade " + release.Tool + " to " + release.Latest + "…")
+		item.SetTooltip("Installed: " + release.Current)
+		return
+	}
+	item.SetTitle("Upgrade " + release.Tool + "…")
+	item.SetTooltip("Installed: " + release.Current)
+}
+
+// upgradeSDKMan shows the installed and latest SDKMan and runs `sdk
+// selfupdate` once confirmed.
+func upgradeSDKMan(item *systray.MenuItem) {
+	release, err := internal.SDKManRelease()
+	if err != nil {
+		showError("Upgrade SDKMan", err)
+		return
+	}
+	showRelease(item, release)
+	if !release.Outdated() {
+		zenity.Info("SDKMan "+release.Current+" is the latest release.", zenity.Title("Upgrade SDKMan"))
+		return
+	}
+	err = zenity.Question("SDKMan "+release.Current+" is installed and "+release.Latest+" is the latest release. Upgrade now?",
+		zenity.Title("Upgrade SDKMan"), zenity.OKLabel("Upgrade"))
+	if err != nil {
+		return
+	}
+	enqueue(internal.Operation{Provider: internal.ProviderSDKMan, Action: "selfupdate", Tool: "sdkman", Version: release.Latest, Run: func() (string, error) {
+		internal.Notify("Upgrade SDKMan", "Upgrading SDKMan to "+release.Latest)
+		out, err := internal.SDKManSelfUpdate(sdkmanInitScript)
+		if err != nil {
+			return out, failed("Upgrade SDKMan", err)
+		}
+		current, err := internal.SDKManScriptVersion()
+		if err != nil {
+			return out, failed("Upgrade SDKMan", err)
+		}
+		internal.Notify("Upgrade SDKMan", "SDKMan upgraded from "+release.Current+" to "+current)
+		release.Current = current
+		showRelease(item, release)
+		reloadMenus(true)
+		return out, nil
+	}})
+}
+
+// upgradeNVM points the NVM installer at the latest release and runs it over
+// the existing install. The new installer is verified like a first install
+// against the hash the app ships for that release, or the one pinned in the
+// settings for it, and the previous installer settings are restored if that
+// fails.
+func upgradeNVM(item *systray.MenuItem) {
+	release, err := internal.NVMRelease()
+	if err != nil {
+		showError("Upgrade NVM", err)
+		return
+	}
+	showRelease(item, release)
+	if !release.Outdated() {
+		zenity.Info("NVM "+release.Current+" is the latest release.", zenity.Title("Upgrade NVM"))
+		return
+	}
+	previous := internal.CurrentConfig().Bootstrap.NVM
+	if !strings.Contains(previous.Source, "{version}") {
+		zenity.Info("NVM is installed from "+previous.Source+", which is one fixed release. Point \"bootstrap\".\"nvm\".\"source\" in the settings at the "+release.Latest+" installer to upgrade.",
+			zenity.Title("Upgrade NVM"))
+		return
+	}
+	target := "v" + release.Latest
+	hash := internal.NVMInstallerSHA256[target]
+	if previous.Version == target && previous.SHA256 != "" {
+		hash = previous.SHA256
+	}
+	if hash == "" {
+		zenity.Info("SDK UI does not know the SHA-256 of the NVM "+target+" installer. Set \"bootstrap\".\"nvm\".\"version\" to \""+target+"\" and \"sha256\" to the checksum of its install.sh in the settings to upgrade.",
+			zenity.Title("Upgrade NVM"))
+		return
+	}
+	err = zenity.Question("NVM "+release.Current+" is installed and "+release.Latest+" is the latest release. Upgrade now?",
+		zenity.Title("Upgrade NVM"), zenity.OKLabel("Upgrade"))
+	if err != nil {
+		return
+	}
+	enqueue(internal.Operation{Provider: internal.ProviderNVM, Action: "selfupdate", Tool: "nvm", Version: release.Latest, Run: func() (string, error) {
+		err := internal.UpdateConfig(func(cfg *internal.Config) {
+			cfg.Bootstrap.NVM.Version = target
+			cfg.Bootstrap.NVM.SHA256 = hash
+		})
+		if err == nil {
+			err = internal.UpgradeNVM()
+		}
+		if err != nil {
+			if restoreErr := internal.UpdateConfig(func(cfg *internal.Config) { cfg.Bootstrap.NVM = previous }); restoreErr != nil {
+				err = fmt.Errorf("%w; restoring the previous installer settings: %v", err, restoreErr)
+			}
+			return "", failed("Upgrade NVM", err)
+		}
+		current, err := internal.NVMVersion()
+		if err != nil {
+			return "", failed("Upgrade NVM", err)
+		}
+		internal.Notify("Upgrade NVM", "NVM upgraded from "+release.Current+" to "+current)
+		release.Current = current
+		showRelease(item, release)
+		reloadMenus(true)
+		return current, nil
+	}})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ncruces/zenity"
+	"os"
+	"sdk-ui-go/internal"
+	"strings"
+)
+
+// showGlobalPackages lists the global npm packages of an installed Node
+// version and copies the chosen ones to another installed version.
+func showGlobalPackages(version string) {
+	title := "Global Packages of node " + version
+	packages, err := internal.GlobalPackages(version)
+	if err != nil && !os.IsNotExist(err) {
+		showError(title, err)
+		return
+	}
+	if len(packages) == 0 {
+		zenity.Info("node "+version+" has no global packages besides npm and corepack.", zenity.Title(title))
+		return
+	}
+	labels := make([]string, len(packages))
+	byLabel := make(map[string]internal.GlobalPackage)
+	for i, p := range packages {
+		labels[i] = p.String()
+		byLabel[labels[i]] = p
+	}
+	chosen, err := zenity.ListMultiple(fmt.Sprintf("node %s has %d global packages. Choose the ones to copy to another version.", version, len(packages)),
+		labels, zenity.Title(title), zenity.CheckList(), zenity.DefaultItems(labels...), zenity.OKLabel("Copy To…"), zenity.CancelLabel("Close"))
+	if err != nil || len(chosen) == 0 {
+		return
+	}
+	var selected []internal.GlobalPackage
+	for _, label := range chosen {
+		selected = append(selected, byLabel[label])
+	}
+	syncGlobalPackages(version, selected)
+}
+
+// syncGlobalPackages asks for the installed Node version to copy packages
+// into and installs the ones it does not have yet.
+func syncGlobalPackages(from string, packages []internal.GlobalPackage) {
+	title := "Copy Global Packages"
+	versions, err := internal.LocalNodeVersions()
+	if err != nil {
+		showError(title, err)
+		return
+	}
+	var targets []string
+	for _, v := range internal.SortCandidates(versions) {
+		if v.Identifier != from {
+			targets = append(targets, v.Identifier)
+		}
+	}
+	if len(targets) == 0 {
+		zenity.Info("There is no other installed Node version to copy to.", zenity.Title(title))
+		return
+	}
+	to, err := zenity.List("Copy the global packages of node "+from+" to:", targets, zenity.Title(title))
+	if err != nil || to == "" {
+		return
+	}
+	existing, err := internal.GlobalPackages(to)
+	if err != nil && !os.IsNotExist(err) {
+		showError(title, err)
+		return
+	}
+	missing := internal.MissingGlobalPackages(packages, existing)
+	if len(missing) == 0 {
+		zenity.Info("node "+to+" already has these packages.", zenity.Title(title))
+		return
+	}
+	specs := make([]string, len(missing))
+	for i, p := range missing {
+		specs[i] = p.Spec()
+	}
+	err = zenity.Question(fmt.Sprintf("Install %d packages into node %s?\n\n%s", len(missing), to, strings.Join(specs, "\n")),
+		zenity.Title(title), zenity.OKLabel("Install"))
+	if err != nil {
+		return
+	}
+	enqueue(internal.Operation{Provider: internal.ProviderNVM, Action: "sync", Tool: internal.NodeWatchKey, Version: to, Run: func() (string, error) {
+		out, err := internal.InstallGlobalPackages(to, missing)
+		if err != nil {
+			return out, failed(title, err)
+		}
+		internal.Notify(title, fmt.Sprintf("Installed %d packages from node %s into node %s", len(missing), from, to))
+		return out, nil
+	}})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"github.com/ncruces/zenity"
+	"log/slog"
+	"sdk-ui-go/internal"
+	"strings"
+)
+
+// resetApp walks the user through taking back what the app added. Nothing
+// is selected up front and every removal keeps a backup.
+func resetApp() {
+	items, err := internal.PlanReset()
+	if err != nil {
+		showError("Reset", err)
+		return
+	}
+	if len(items) == 0 {
+		zenity.Info("SDK UI has not added anything that is still there.", zenity.Title("Reset"))
+		return
+	}
+	var descriptions []string
+	for _, item := range items {
+		descriptions = append(descriptions, item.Description)
+	}
+	chosen, err := zenity.ListMultiple("Choose what SDK UI should remove. Shell files are backed up next to themselves, tool directories are renamed to a .bak directory and autostart entries are moved to the app's backups folder.",
+		descriptions, zenity.Title("Reset"), zenity.CheckList())
+	if err != nil || len(chosen) == 0 {
+		return
+	}
+	err = zenity.Question("The following will be removed:\n\n"+strings.Join(chosen, "\n"),
+		zenity.Title("Reset"), zenity.OKLabel("Remove"))
+	if err != nil {
+		return
+	}
+
+	selected := make(map[string]bool)
+	for _, description := range chosen {
+		selected[description] = true
+	}
+	var errs []error
+	removed := make(map[string]bool)
+	for _, item := range items {
+		if !selected[item.Description] {
+			continue
+		}
+		if err := item.Remove(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed[item.Kind] = true
+	}
+	if removed[internal.ResetShell] || removed[internal.ResetTool] {
+		// Without this the next start would ask again or reinstall what
+		// was just removed.
+		err := internal.UpdateConfig(func(cfg *internal.Config) {
+			if removed[internal.ResetShell] {
+				cfg.ShellIntegration = internal.ShellIntegrationNo
+			}
+			if removed[internal.ResetTool] {
+				cfg.AutoInstall = false
+			}
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if removed[internal.ResetTool] {
+		reloadMenus(false)
+	}
+	if len(errs) > 0 {
+		showError("Reset", errors.Join(errs...))
+		return
+	}
+	slog.Info("reset finished", "items", len(chosen))
+	internal.Notify("Reset", "Removed the selected items, backups were kept")
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"github.com/getlantern/systray"
+	"log/slog"
+	"os"
+	"sdk-ui-go/internal"
+	"time"
+)
+
+const (
+	// settingsPollInterval is how soon a paused job notices it was turned on.
+	settingsPollInterval = time.Hour
+	// offlineRetryInterval is how soon a job skipped while offline is tried
+	// again, so it runs shortly after the connection is back.
+	offlineRetryInterval = time.Minute
+)
+
+// every runs job now and then once per interval. interval is re-read after
+// each run so settings changes apply; zero pauses the job. Runs are skipped
+// while offline and retried every minute until back online.
+func every(name string, interval func() time.Duration, job func()) {
+	for {
+		wait := interval()
+		if wait <= 0 {
+			wait = settingsPollInterval
+		} else if offline.Load() {
+			slog.Debug("skipping scheduled job while offline", "job", name)
+			wait = min(wait, offlineRetryInterval)
+		} else {
+			slog.Debug("running scheduled job", "job", name)
+			job()
+		}
+		time.Sleep(wait)
+	}
+}
+
+func updateCheckInterval() time.Duration {
+	return time.Duration(internal.CurrentConfig().UpdateCheckHours) * time.Hour
+}
+
+// checkForUpdates refreshes the remote lists of installed tools that are
+// older than half the interval, so the check right after start reuses what
+// the menus just loaded, then raises one notification for everything new.
+// The lists are fetched before comparing, so the check never works on the
+// lists it was meant to replace.
+func checkForUpdates(sdkmanUpgradeItem *systray.MenuItem, nvmUpgradeItem *systray.MenuItem) {
+	cfg := internal.CurrentConfig()
+	installed := make(map[string]bool)
+	if names, err := internal.LocalCandidateNames(); err == nil {
+		for _, name := range names {
+			installed[name] = true
+		}
+	}
+	if versions, err := internal.LocalNodeVersions(); err == nil && len(versions) > 0 {
+		installed[nodeMenuKey] = true
+	}
+	var refreshes []func()
+	menuSourcesMu.Lock()
+	for key, source := range menuSources {
+		entry, ok := internal.LoadCache(source.cacheKey)
+		if installed[key] && (!ok || entry.Expired(updateCheckInterval()/2)) {
+			refreshes = append(refreshes, func() {
+				if _, _, err := internal.RefreshCandidateCache(source.cacheKey, entry, source.fetch); err != nil {
+					slog.Warn("refreshing versions for the update check", "tool", key, "err", err)
+					return
+				}
+				// The cache is fresh now, so this only redraws the menu.
+				source.load()
+			})
+		}
+	}
+	menuSourcesMu.Unlock()
+	runLoads(refreshes, time.Now())
+	updatesMenu.refresh()
+	// Ages under Stale Versions move on even when nothing is installed.
+	scanDiskUsage()
+
+	var result internal.CheckResult
+	updates, err := internal.CheckUpdates()
+	if err != nil {
+		slog.Error("checking for updates", "err", err)
+	}
+	result.Updates = updates
+	if cfg.Providers.SDKMan {
+		release, err := internal.SDKManRelease()
+		if err != nil && !os.IsNotExist(err) {
+			slog.Warn("checking for a new SDKMan", "err", err)
+		}
+		result.Releases = append(result.Releases, release)
+		showRelease(sdkmanUpgradeItem, release)
+	}
+	if cfg.Providers.NVM {
+		release, err := internal.NVMRelease()
+		if err != nil {
+			slog.Warn("checking for a new NVM", "err", err)
+		}
+		result.Releases = append(result.Releases, release)
+		showRelease(nvmUpgradeItem, release)
+	}
+	if result.NewLTS, err = internal.NewLTSLines(); err != nil {
+		slog.Error("checking for new LTS lines", "err", err)
+	}
+
+	slog.Info("update check finished", "updates", len(result.Updates), "new_lts", len(result.NewLTS))
+	fresh, err := result.Unnotified()
+	if err != nil {
+		slog.Error("reading notified updates", "err", err)
+	}
+	if summary := fresh.Summary(); summary != "" {
+		internal.Notify("Updates Available", summary)
+	}
+}
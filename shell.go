@@ -0,0 +1,154 @@
+package main
+
+import (
+	"github.com/getlantern/systray"
+	"github.com/ncruces/zenity"
+	"log/slog"
+	"sdk-ui-go/internal"
+	"strings"
+)
+
+func addShellIntegrationMenu() {
+	shellItem := systray.AddMenuItem("Shell Integration", "")
+	reviewItem := shellItem.AddSubMenuItem("Review && Apply…", "Preview the SDKMan/NVM setup for your shell rc files")
+	removeItem := shellItem.AddSubMenuItem("Remove from Shell Files", "Take the SDK UI block out of your shell rc files")
+	go func() {
+		for {
+			select {
+			case <-reviewItem.ClickedCh:
+				checkShellIntegration(true)
+			case <-removeItem.ClickedCh:
+				removeShellIntegration()
+			}
+		}
+	}()
+}
+
+// checkShellIntegration keeps the managed rc blocks current once the user
+// has agreed, asks on first start, and does nothing after "Never". Only
+// rewrites of a block that is already there are applied without asking;
+// anything touching a file's own lines, such as a file that lost its block
+// or a newly detected shell, is shown for consent again. With review set
+// the dialog is shown regardless of the saved answer.
+func checkShellIntegration(review bool) {
+	cfg := internal.CurrentConfig()
+	if cfg.ShellIntegration == internal.ShellIntegrationNo && !review {
+		return
+	}
+	changes, err := internal.PlanShellIntegration()
+	if err != nil {
+		showError("Shell Integration", err)
+		return
+	}
+	pending := pendingShellChanges(changes)
+	if len(pending) == 0 {
+		if review {
+			zenity.Info("Your shell config files are up to date.", zenity.Title("Shell Integration"))
+		}
+		return
+	}
+	if cfg.ShellIntegration == internal.ShellIntegrationYes && !review {
+		pending = applyBlockRewrites(pending)
+		if len(pending) == 0 {
+			return
+		}
+	}
+
+	err = zenity.Question("SDK UI can set up SDKMan and NVM in your shell. The following changes would be made, and each file is backed up first:\n\n"+shellDiff(pending),
+		zenity.Title("Shell Integration"), zenity.OKLabel("Apply"), zenity.CancelLabel("Not now"), zenity.ExtraButton("Never"))
+	switch err {
+	case nil:
+		if applyShellChanges(pending) {
+			saveShellIntegration(internal.ShellIntegrationYes)
+		}
+	case zenity.ErrExtraButton:
+		saveShellIntegration(internal.ShellIntegrationNo)
+	default:
+		slog.Info("shell integration postponed")
+	}
+}
+
+// refreshShellIntegration rewrites the managed blocks once the user agreed,
+// since the fish and Nushell snippets pin the default Node.
+func refreshShellIntegration() {
+	if internal.CurrentConfig().ShellIntegration != internal.ShellIntegrationYes {
+		return
+	}
+	checkShellIntegration(false)
+}
+
+// applyBlockRewrites applies the changes that only rewrite an existing
+// managed block and returns the rest.
+func applyBlockRewrites(changes []internal.ShellChange) []internal.ShellChange {
+	var rest []internal.ShellChange
+	for _, change := range changes {
+		if !change.BlockOnly() {
+			rest = append(rest, change)
+			continue
+		}
+		if err := internal.ApplyShellChange(change); err != nil {
+			slog.Error("updating shell integration", "path", change.Shell.RCFile, "err", err)
+		}
+	}
+	return rest
+}
+
+func removeShellIntegration() {
+	changes, err := internal.PlanShellRemoval()
+	if err != nil {
+		showError("Shell Integration", err)
+		return
+	}
+	pending := pendingShellChanges(changes)
+	if len(pending) == 0 {
+		zenity.Info("None of your shell config files contain the SDK UI block.", zenity.Title("Shell Integration"))
+		return
+	}
+	err = zenity.Question("The SDK UI block will be removed, and each file is backed up first:\n\n"+shellDiff(pending),
+		zenity.Title("Shell Integration"), zenity.OKLabel("Remove"))
+	if err != nil {
+		return
+	}
+	if applyShellChanges(pending) {
+		saveShellIntegration(internal.ShellIntegrationNo)
+	}
+}
+
+func pendingShellChanges(changes []internal.ShellChange) []internal.ShellChange {
+	var pending []internal.ShellChange
+	for _, change := range changes {
+		if change.Changed() {
+			pending = append(pending, change)
+		}
+	}
+	return pending
+}
+
+func applyShellChanges(changes []internal.ShellChange) bool {
+	for _, change := range changes {
+		if err := internal.ApplyShellChange(change); err != nil {
+			showError("Shell Integration", err)
+			return false
+		}
+	}
+	internal.Notify("Shell Integration", "Open a new terminal to pick up the changes")
+	return true
+}
+
+func saveShellIntegration(answer string) {
+	if err := internal.UpdateConfig(func(cfg *internal.Config) { cfg.ShellIntegration = answer }); err != nil {
+		showError("Shell Integration", err)
+	}
+}
+
+func shellDiff(changes []internal.ShellChange) string {
+	var diffs []string
+	for _, change := range changes {
+		diffs = append(diffs, change.Diff())
+	}
+	diff := strings.Join(diffs, "\n")
+	if len(diff) > maxErrorDetails {
+		diff = diff[:maxErrorDetails] + "…"
+	}
+	return diff
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"github.com/getlantern/systray"
+	"github.com/ncruces/zenity"
+	"log/slog"
+	"sdk-ui-go/internal"
+	"strings"
+	"sync"
+	"time"
+)
+
+const staleMenuSize = 10
+
+var staleMenu *StaleMenu
+
+// StaleMenu lists the versions nobody used for stale_after_days, oldest
+// first, in a fixed set of slots since systray cannot remove items.
+type StaleMenu struct {
+	item          *systray.MenuItem
+	slots         []*systray.MenuItem
+	removeAllItem *systray.MenuItem
+	mu            sync.Mutex
+	versions      []internal.VersionActivity
+}
+
+func newStaleMenu(item *systray.MenuItem) *StaleMenu {
+	menu := &StaleMenu{item: item}
+	for i := 0; i < staleMenuSize; i++ {
+		slot := item.AddSubMenuItem("", "")
+		removeItem := slot.AddSubMenuItem("Remove", "")
+		slot.Hide()
+		menu.slots = append(menu.slots, slot)
+		go func(i int) {
+			for range removeItem.ClickedCh {
+				menu.remove(i)
+			}
+		}(i)
+	}
+	menu.removeAllItem = item.AddSubMenuItem("Remove All…", "")
+	go func() {
+		for range menu.removeAllItem.ClickedCh {
+			menu.removeAll()
+		}
+	}()
+	item.SetTitle("Stale Versions")
+	item.Disable()
+	return menu
+}
+
+// refresh recomputes the stale versions from a fresh disk usage scan.
+func (m *StaleMenu) refresh(usages []internal.VersionUsage) {
+	days := internal.CurrentConfig().StaleAfterDays
+	stale, err := internal.StaleVersions(usages, time.Duration(days)*24*time.Hour)
+	if err != nil {
+		slog.Error("finding stale versions", "err", err)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions = stale
+	if len(stale) == 0 {
+		m.item.SetTitle("Stale Versions")
+		m.item.SetTooltip(fmt.Sprintf("Every installed version was used in the last %d days", days))
+		m.item.Disable()
+		return
+	}
+	m.item.SetTitle(fmt.Sprintf("Stale Versions (%d)", len(stale)))
+	m.item.SetTooltip(fmt.Sprintf("Versions not used for %d days", days))
+	m.item.Enable()
+	for i, slot := range m.slots {
+		if i >= len(stale) {
+			slot.Hide()
+			continue
+		}
+		slot.SetTitle(staleTitle(stale[i]))
+		slot.SetTooltip("Last used " + stale[i].LastUsed.Format("2006-01-02"))
+		slot.Show()
+	}
+}
+
+func staleTitle(v internal.VersionActivity) string {
+	return fmt.Sprintf("%s %s · %d days · %s", v.Tool, v.Version, int(v.Age().Hours()/24), internal.FormatSize(v.Size))
+}
+
+func (m *StaleMenu) remove(i int) {
+	m.mu.Lock()
+	if i >= len(m.versions) {
+		m.mu.Unlock()
+		return
+	}
+	v := m.versions[i]
+	m.mu.Unlock()
+	if isCheckedInMenu(v.Tool, v.Version) {
+		return
+	}
+	enqueueUninstall(v.Tool, v.Version)
+}
+
+func (m *StaleMenu) removeAll() {
+	m.mu.Lock()
+	versions := append([]internal.VersionActivity{}, m.versions...)
+	m.mu.Unlock()
+	var titles []string
+	var freed int64
+	for _, v := range versions {
+		titles = append(titles, staleTitle(v))
+		freed += v.Size
+	}
+	err := zenity.Question(fmt.Sprintf("Uninstall %d stale versions and free %s?\n\n%s", len(versions), internal.FormatSize(freed), strings.Join(titles, "\n")),
+		zenity.Title("Stale Versions"), zenity.OKLabel("Uninstall"))
+	if err != nil {
+		return
+	}
+	for _, v := range versions {
+		if !isCheckedInMenu(v.Tool, v.Version) {
+			enqueueUninstall(v.Tool, v.Version)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"github.com/ncruces/zenity"
+	"log/slog"
+	"sdk-ui-go/internal"
+	"strings"
+	"sync"
+)
+
+var (
+	diskUsage   = make(map[string]int64)
+	diskUsageMu sync.Mutex
+	scanMu      sync.Mutex
+)
+
+// versionHome is where menu's version is installed.
+func versionHome(menu *VersionMenu) string {
+	if menu.Title == nodeMenuKey {
+		return internal.LocalNodeHome(menu.Version)
+	}
+	return internal.LocalCandidateHome(menu.Title, menu.Version)
+}
+
+func sizeTooltip(menu *VersionMenu) string {
+	diskUsageMu.Lock()
+	defer diskUsageMu.Unlock()
+	if size, ok := diskUsage[versionHome(menu)]; ok {
+		return "Uses " + internal.FormatSize(size)
+	}
+	return ""
+}
+
+// scanDiskUsage re-sizes the installed versions and shows the sizes in their
+// tooltips. Only one scan runs at a time.
+func scanDiskUsage() ([]internal.VersionUsage, error) {
+	scanMu.Lock()
+	defer scanMu.Unlock()
+	usages, err := internal.ScanDiskUsage()
+	if err != nil {
+		slog.Error("scanning disk usage", "err", err)
+		return nil, err
+	}
+	sizes := make(map[string]int64)
+	for _, usage := range usages {
+		sizes[usage.Path] = usage.Size
+	}
+	diskUsageMu.Lock()
+	diskUsage = sizes
+	diskUsageMu.Unlock()
+	if staleMenu != nil {
+		staleMenu.refresh(usages)
+	}
+
+	candidateMu.Lock()
+	defer candidateMu.Unlock()
+	for _, menus := range candidate {
+		for _, menu := range menus {
+			if _, ok := sizes[versionHome(menu)]; ok {
+				menu.MenuItem.SetTooltip(versionTooltip(menu))
+			}
+		}
+	}
+	return usages, nil
+}
+
+// showStorage lists the installed versions by size and uninstalls the ones
+// the user picks. Default versions are left out so they cannot be removed.
+func showStorage() {
+	usages, err := scanDiskUsage()
+	if err != nil {
+		showError("Storage", err)
+		return
+	}
+	var total int64
+	var labels []string
+	byLabel := make(map[string]internal.VersionUsage)
+	for _, usage := range usages {
+		total += usage.Size
+		if usage.Use {
+			continue
+		}
+		label := fmt.Sprintf("%s   %s %s", internal.FormatSize(usage.Size), usage.Tool, usage.Version)
+		labels = append(labels, label)
+		byLabel[label] = usage
+	}
+	if len(labels) == 0 {
+		zenity.Info(fmt.Sprintf("Installed versions use %s, all of it by default versions.", internal.FormatSize(total)), zenity.Title("Storage"))
+		return
+	}
+	chosen, err := zenity.ListMultiple(fmt.Sprintf("Installed versions use %s. Choose the versions to uninstall; default versions are not listed.", internal.FormatSize(total)),
+		labels, zenity.Title("Storage"), zenity.CheckList())
+	if err != nil || len(chosen) == 0 {
+		return
+	}
+	var freed int64
+	for _, label := range chosen {
+		freed += byLabel[label].Size
+	}
+	err = zenity.Question(fmt.Sprintf("Uninstall %d versions and free %s?\n\n%s", len(chosen), internal.FormatSize(freed), strings.Join(chosen, "\n")),
+		zenity.Title("Storage"), zenity.OKLabel("Uninstall"))
+	if err != nil {
+		return
+	}
+	for _, label := range chosen {
+		usage := byLabel[label]
+		enqueueUninstall(usage.Tool, usage.Version)
+	}
+}
+
+// enqueueUninstall queues the removal of an installed version on behalf of
+// a bulk action; the version's menu item is updated once it is gone.
+func enqueueUninstall(tool string, version string) {
+	op := internal.Operation{Provider: internal.ProviderSDKMan, Action: "uninstall", Tool: tool, Version: version}
+	key, cacheKey := tool, internal.SDKManCacheKey(tool)
+	uninstall := func() (string, error) { return internal.UninstallCandidate(tool, version, sdkmanInitScript) }
+	if tool == internal.NodeWatchKey {
+		op.Provider = internal.ProviderNVM
+		key, cacheKey = nodeMenuKey, internal.NodeCacheKey
+		uninstall = func() (string, error) { return internal.UninstallNode(version) }
+	}
+	op.Run = func() (string, error) {
+		out, err := uninstall()
+		if err != nil {
+			return out, failed("Uninstall failed", err)
+		}
+		internal.Notify("Uninstall", tool+" "+version+" has removed")
+		candidateMu.Lock()
+		for _, menu := range candidate[key] {
+			if menu.Version == version {
+				menu.SetState(false, false)
+			}
+		}
+		candidateMu.Unlock()
+		internal.InvalidateCache(cacheKey)
+		return out, nil
+	}
+	enqueue(op)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"github.com/getlantern/systray"
+	"github.com/ncruces/zenity"
+	"log/slog"
+	"sdk-ui-go/internal"
+	"sync"
+)
+
+const updatesMenuSize = 10
+
+var updatesMenu *UpdatesMenu
+
+// UpdatesMenu lists newer patch releases of installed lines and badges the
+// tray title with their count.
+type UpdatesMenu struct {
+	item    *systray.MenuItem
+	slots   []*systray.MenuItem
+	mu      sync.Mutex
+	updates []internal.VersionUpdate
+}
+
+func newUpdatesMenu(item *systray.MenuItem) *UpdatesMenu {
+	menu := &UpdatesMenu{item: item}
+	for i := 0; i < updatesMenuSize; i++ {
+		slot := item.AddSubMenuItem("", "")
+		upgradeItem := slot.AddSubMenuItem("Upgrade…", "")
+		slot.Hide()
+		menu.slots = append(menu.slots, slot)
+		go func(i int) {
+			for range upgradeItem.ClickedCh {
+				menu.upgrade(i)
+			}
+		}(i)
+	}
+	item.Disable()
+	return menu
+}
+
+// refresh re-checks the installed versions against the cached remote lists.
+func (m *UpdatesMenu) refresh() {
+	updates, err := internal.CheckUpdates()
+	if err != nil {
+		slog.Error("checking for updates", "err", err)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updates = updates
+	if len(updates) == 0 {
+		m.item.SetTitle("Updates Available")
+		m.item.Disable()
+		systray.SetTitle("SDK")
+		systray.SetTooltip("SDK UI")
+	} else {
+		m.item.SetTitle(fmt.Sprintf("Updates Available (%d)", len(updates)))
+		m.item.Enable()
+		systray.SetTitle(fmt.Sprintf("SDK ↑%d", len(updates)))
+		systray.SetTooltip(fmt.Sprintf("SDK UI: %d updates available", len(updates)))
+	}
+	for i, slot := range m.slots {
+		if i >= len(updates) {
+			slot.Hide()
+			continue
+		}
+		title := updates[i].String()
+		if updates[i].Default {
+			title += " (default)"
+		}
+		slot.SetTitle(title)
+		slot.Show()
+	}
+}
+
+func (m *UpdatesMenu) upgrade(i int) {
+	m.mu.Lock()
+	if i >= len(m.updates) {
+		m.mu.Unlock()
+		return
+	}
+	update := m.updates[i]
+	m.mu.Unlock()
+
+	text := "Install " + update.Tool + " " + update.Latest + "?"
+	if update.Default {
+		text += " It becomes the default instead of " + update.Current + "."
+	}
+	err := zenity.Question(text, zenity.Title("Upgrade "+update.Tool), zenity.OKLabel("Upgrade"),
+		zenity.ExtraButton("Upgrade and Remove "+update.Current))
+	switch err {
+	case nil:
+		enqueueUpgrade(update, false)
+	case zenity.ErrExtraButton:
+		enqueueUpgrade(update, true)
+	}
+}
+
+// enqueueUpgrade installs the newer version, moves the default along when
+// the old one was the default, and removes the old one if asked to.
+func enqueueUpgrade(update internal.VersionUpdate, removeOld bool) {
+	op := internal.Operation{Provider: internal.ProviderSDKMan, Action: "upgrade", Tool: update.Tool, Version: update.Latest, Detail: internal.UpgradeDetail(update.Current)}
+	cacheKey := internal.SDKManCacheKey(update.Tool)
+	install := func() (string, error) {
+		if update.Default {
+			return internal.UseCandidate(update.Tool, update.Latest, sdkmanInitScript)
+		}
+		return internal.InstallCandidate(update.Tool, update.Latest, sdkmanInitScript)
+	}
+	uninstall := func() (string, error) {
+		return internal.UninstallCandidate(update.Tool, update.Current, sdkmanInitScript)
+	}
+	if update.Tool == internal.NodeWatchKey {
+		op.Provider = internal.ProviderNVM
+		cacheKey = internal.NodeCacheKey
+		install = func() (string, error) {
+			if update.Default {
+				return internal.InstallNode(update.Latest)
+			}
+			out, err := internal.InstallNodeVersion(update.Latest)
+			if err != nil || !internal.CurrentConfig().ReinstallGlobalPackages {
+				return out, err
+			}
+			// The default's packages are only carried over to a new
+			// default, so the old line brings along its own.
+			_, synced, err := internal.SyncGlobalPackages(update.Current, update.Latest)
+			return out + synced, err
+		}
+		uninstall = func() (string, error) { return internal.UninstallNode(update.Current) }
+	}
+	op.Run = func() (string, error) {
+		internal.Notify("Upgrade", "Installing "+update.Tool+" "+update.Latest)
+		out, err := install()
+		if err != nil {
+			return out, failed("Upgrade failed", err)
+		}
+		if removeOld {
+			removed, err := uninstall()
+			out += removed
+			if err != nil {
+				return out, failed("Removing "+update.Current+" failed", err)
+			}
+		}
+		internal.InvalidateCache(cacheKey)
+		internal.Notify("Upgrade", update.Tool+" "+update.Latest+" has installed")
+		reloadMenus(false)
+		return out, nil
+	}
+	enqueue(op)
+}